@@ -1,6 +1,11 @@
 package argv
 
 import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/oddbit-project/blueprint/utils"
 	"reflect"
 	"strconv"
 	"strings"
@@ -8,249 +13,609 @@ import (
 )
 
 const (
-	annotationTag = "argv"
+	annotationTag    = "argv"
+	defaultSeparator = ","
+
+	// internal conversion error, signals field type is not supported
+	errNotSupported = utils.Error("type not supported")
 )
 
 // field mapper
 type FieldParser func(in string) (any, error)
 
-// struct types that should not be recursively parsed
+// ArgvUnmarshaler is implemented by types parsing their own argument values
+// It takes precedence over encoding.TextUnmarshaler and flag.Value, for types requiring argv-specific behaviour.
+type ArgvUnmarshaler interface {
+	UnmarshalArgv(value string) error
+}
+
 var (
-	reservedFieldTypes = []string{"time.Time"}
-	fieldParser        = make(map[string]FieldParser, 0)
+	argvUnmarshalerType = typeOf[ArgvUnmarshaler]()
+	textUnmarshalerType = typeOf[encoding.TextUnmarshaler]()
+	flagValueType       = typeOf[flag.Value]()
 )
 
-// add a custom field parser
+// add a custom field parser to the default parser, by type name, e.g. "net.IP"
+// Parsers are resolved when a struct is first parsed; they should be registered on program initialization.
+//
+// Deprecated: type names are ambiguous, e.g. for packages with the same name, and for generic types; use
+// RegisterParser instead.
 func AddParser(in string, fn FieldParser) {
-	fieldParser[in] = fn
+	defaultParser.AddParser(in, fn)
 }
 
-func AddReservedType(t string) {
-	reservedFieldTypes = append(reservedFieldTypes, t)
+// RegisterParser adds a custom field parser to the default parser, for fields of type T
+// Parsers are resolved when a struct is first parsed; they should be registered on program initialization.
+func RegisterParser[T any](fn func(in string) (T, error)) {
+	defaultParser.addTypeParser(typeOf[T](), typedSetter(fn))
 }
 
-// Check if field type name is reserved
-func isReserved(t string) bool {
-	for _, v := range reservedFieldTypes {
-		if v == t {
-			return true
-		}
-	}
-	return false
+// RegisterEnum registers the names of the values of type T in the default parser, such as named constants
+// Fields of type T accept the registered names, and reject any other value; the names are also the field choices,
+// in the schema and usage output. Enums should be registered on program initialization.
+func RegisterEnum[T any](values map[string]T) {
+	defaultParser.addEnum(typeOf[T](), newEnum(values))
 }
 
+// add a struct type to the default parser that should not be recursively parsed, by type name
+func AddReservedType(t string) {
+	defaultParser.AddReservedType(t)
+}
+
+// ParseNames returns the named args declared in dest; aliases are grouped per field, e.g. "days|d"
+// See ParseSchema for a full description of the declared args.
 func ParseNames(dest any) ([]string, error) {
-	t := reflect.TypeOf(dest)
-	v := reflect.ValueOf(dest)
-	if t.Kind() != reflect.Ptr {
-		return nil, ErrInvalidDest
+	return defaultParser.ParseNames(dest)
+}
+
+// ParseDefaults returns the default values declared in dest tags, by arg name
+// See ParseSchema for a full description of the declared args.
+func ParseDefaults(dest any) (map[string]string, error) {
+	return defaultParser.ParseDefaults(dest)
+}
+
+// Parse parses argv into dest, like ParseArgv, and reports which fields were assigned, and how
+// Pointer fields are only allocated if assigned, so both Parse and pointer fields distinguish omitted arguments
+// from zero values.
+func Parse(dest any, argv []string, opts ...Option) (*ParseResult, error) {
+	return defaultParser.Parse(dest, argv, opts...)
+}
+
+// ParseArgv parses argv into dest, a pointer to a tagged struct
+// By default, unknown arguments are rejected; see WithStrict. If -h or --help is present, and not declared
+// by dest, ErrHelp is returned; see Usage. An empty argv is accepted if environment variables and defaults fill
+// every required field; otherwise, ErrEmptyArgs is returned.
+func ParseArgv(dest any, argv []string, opts ...Option) error {
+	return defaultParser.ParseArgv(dest, argv, opts...)
+}
+
+// parse tokenized arguments into dest
+func parseTokens(dest any, s *Schema, cfg *config, tokens []token, positional []string, result *ParseResult) error {
+	var err error
+	args := make(map[string][]string, len(tokens))
+	counters := make(map[string]int, 0)
+	unknown := make([]token, 0)
+	for _, tok := range tokens {
+		if tok.spec == nil {
+			unknown = append(unknown, tok)
+			continue
+		}
+		if tok.spec.Counter {
+			// each valueless occurrence increments the counter; explicit values reset it
+			if len(tok.value) == 0 {
+				counters[tok.name]++
+			} else if counters[tok.name], err = strconv.Atoi(tok.value); err != nil {
+				return ErrInvalidValue(tok.name, err)
+			}
+			args[tok.name] = []string{strconv.Itoa(counters[tok.name])}
+			continue
+		}
+		args[tok.name] = append(args[tok.name], tok.value)
 	}
-	t = t.Elem()
-	v = v.Elem()
-	if t.Kind() != reflect.Struct {
-		return nil, ErrInvalidDestType
+	// report typos first, as a misspelled arg usually causes other errors, such as a missing value
+	if err := checkUnknown(s, cfg, unknown); err != nil {
+		return err
 	}
-	result := make([]string, 0)
+	if err := parseArgv(dest, s, cfg, args, positional, result); err != nil {
+		return err
+	}
+	return parseUnknown(dest, s, cfg, unknown)
+}
 
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		kind := v.Field(i).Kind()
-		reserved := isReserved(field.Type.String())
-		if kind == reflect.Struct && !reserved {
-			vals, err := ParseNames(v.Field(i).Addr().Interface())
-			if err != nil {
-				return nil, err
+// collect unknown arguments into the #extra field, if any; otherwise, reject them in strict mode
+func parseUnknown(dest any, s *Schema, cfg *config, unknown []token) error {
+	if len(unknown) == 0 {
+		return nil
+	}
+	if s.Extra != nil {
+		field := s.Extra.field(reflect.ValueOf(dest).Elem())
+		if field.Kind() == reflect.Map {
+			if field.IsNil() {
+				field.Set(reflect.MakeMap(field.Type()))
 			}
-			result = append(result, vals...)
-		} else {
-			fieldName, _ := parseTag(t.Field(i).Tag.Get(annotationTag))
-			if len(fieldName) == 0 {
-				continue
+			for _, tok := range unknown {
+				field.SetMapIndex(reflect.ValueOf(tok.name), reflect.ValueOf(tok.value))
 			}
-			if !v.Field(i).CanInterface() {
-				continue
+			return nil
+		}
+		for _, tok := range unknown {
+			for _, raw := range tok.raw {
+				field.Set(reflect.Append(field, reflect.ValueOf(raw)))
 			}
-			result = append(result, fieldName)
 		}
+		return nil
 	}
-	return result, nil
+	if !cfg.strict {
+		return nil
+	}
+	return unknownArgsError(s, unknown)
 }
 
-func extractArgs(args []string) (map[string]string, error) {
-	if len(args)%2 > 0 {
-		return nil, ErrInvalidParameterCount
+// check for unknown arguments, without assigning any value, so typos are reported before other errors
+func checkUnknown(s *Schema, cfg *config, tokens []token) error {
+	unknown := make([]token, 0)
+	for _, tok := range tokens {
+		if tok.spec == nil {
+			unknown = append(unknown, tok)
+		}
 	}
-	result := make(map[string]string, 0)
-	i := 0
-	for i < len(args) {
-		argName := args[i]
-		if strings.HasPrefix(argName, "--") {
-			argName = argName[2:]
-		} else if strings.HasPrefix(argName, "-") {
-			argName = argName[1:]
+	if len(unknown) == 0 || s.Extra != nil || !cfg.strict {
+		return nil
+	}
+	return unknownArgsError(s, unknown)
+}
+
+// build the error for unknown arguments, with suggestions from the known names
+func unknownArgsError(s *Schema, unknown []token) error {
+	known := s.names()
+	names := make([]string, 0, len(unknown))
+	suggestions := make(map[string][]string, 0)
+	for _, tok := range unknown {
+		names = append(names, tok.name)
+		if s := suggest(tok.name, known); len(s) > 0 {
+			suggestions[tok.name] = s
 		}
-		result[argName] = args[i+1]
-		i += 2
 	}
-	return result, nil
+	return ErrUnknownArgs(names, suggestions)
 }
 
-func parseTag(tag string) (string, bool) {
-	if len(tag) == 0 {
-		return "", false
+func parseArgv(dest any, s *Schema, cfg *config, args map[string][]string, positional []string, result *ParseResult) error {
+	v := reflect.ValueOf(dest).Elem()
+	for _, spec := range s.Fields {
+		if spec.IsPositional() {
+			continue
+		}
+		values, ok := args[spec.Name]
+		source := SourceArg
+		envName := ""
+		// fallback to environment, if available
+		if !ok {
+			if envName = cfg.envName(spec); len(envName) > 0 {
+				var fValue string
+				if fValue, ok = cfg.envLookup(envName); ok {
+					values = []string{fValue}
+					source = SourceEnv
+				}
+			}
+		}
+		if !ok {
+			if !spec.Optional {
+				return ErrMissingValue(spec.Name)
+			}
+			continue
+		}
+
+		field := spec.field(v)
+		// field has a tag, but it is not settable
+		if field.Kind() != reflect.Interface {
+			if !field.CanSet() {
+				return ErrReadOnly(spec.Name)
+			}
+		}
+		if source == SourceEnv {
+			if err := spec.assign(field, values...); err != nil {
+				return spec.wrapError(fmt.Errorf("environment variable %s: %w", envName, err))
+			}
+			if err := spec.validate(field); err != nil {
+				return err
+			}
+		} else if err := spec.setValue(field, values...); err != nil {
+			return err
+		}
+		result.set(spec, source)
 	}
-	toks := strings.Split(tag, ",")
-	if len(toks) == 1 {
-		return toks[0], false
+	if err := bindPositional(v, s, positional, result); err != nil {
+		return err
 	}
-	return toks[0], toks[1] == "optional"
+	if err := applyDefaults(v, s, result); err != nil {
+		return err
+	}
+	return s.checkConstraints(result)
 }
 
-func ParseArgv(dest any, argv []string) error {
-	if len(argv) == 0 {
-		return ErrEmptyArgs
+// assign default values to the fields not assigned otherwise, using the same conversion as argument values
+// Fields of nil nested struct pointers are skipped, so defaults alone do not allocate them.
+func applyDefaults(v reflect.Value, s *Schema, result *ParseResult) error {
+	for _, spec := range s.Fields {
+		if !spec.HasDefault || result.Source(spec.Path) != SourceNone {
+			continue
+		}
+		field, ok := spec.allocatedField(v)
+		if !ok {
+			continue
+		}
+		// defaults are checked when the schema is compiled
+		if err := spec.setValue(field, spec.Default); err != nil {
+			return err
+		}
+		result.set(spec, SourceDefault)
+	}
+	return nil
+}
+
+// assign positional values to their slots; extra values go to the #rest field, if any
+func bindPositional(v reflect.Value, s *Schema, values []string, result *ParseResult) error {
+	for i, spec := range s.Positional {
+		if i >= len(values) {
+			if !spec.Optional {
+				return ErrMissingPositional(positionalLabel(spec))
+			}
+			continue
+		}
+		if err := spec.setValue(spec.field(v), values[i]); err != nil {
+			return err
+		}
+		result.set(spec, SourceArg)
 	}
-	args, err := extractArgs(argv)
-	if err != nil {
+	if len(values) <= len(s.Positional) {
+		if s.Rest != nil && !s.Rest.Optional {
+			return ErrMissingPositional(positionalLabel(s.Rest))
+		}
+		return nil
+	}
+	values = values[len(s.Positional):]
+	if s.Rest == nil {
+		return ErrUnexpectedArg(values[0])
+	}
+	field := s.Rest.field(v)
+	items := reflect.MakeSlice(field.Type(), len(values), len(values))
+	for i, value := range values {
+		if err := s.Rest.wrapError(s.Rest.setElem(items.Index(i), value)); err != nil {
+			return err
+		}
+	}
+	field.Set(items)
+	if err := s.Rest.validate(field); err != nil {
 		return err
 	}
-	return parseArgv(dest, args)
+	result.set(s.Rest, SourceArg)
+	return nil
 }
 
-func parseArgv(dest any, args map[string]string) error {
-	if len(args) == 0 {
-		return nil
+// convert string values to the field type, assign them, and check the field validation rules
+func (f *FieldSpec) setValue(field reflect.Value, values ...string) error {
+	if err := f.assign(field, values...); err != nil {
+		return f.wrapError(err)
 	}
+	return f.validate(field)
+}
 
-	t := reflect.TypeOf(dest)
-	v := reflect.ValueOf(dest)
-	if t.Kind() != reflect.Ptr {
-		return ErrInvalidDest
+// convert string values to the field type, and assign them; returns the raw conversion error, if any
+// Slice and map fields receive the items of all values, split by the field separator; other fields receive the
+// last value.
+func (f *FieldSpec) assign(field reflect.Value, values ...string) error {
+	if !f.multi {
+		return f.set(field, values[len(values)-1])
 	}
-	t = t.Elem()
-	v = v.Elem()
-	if t.Kind() != reflect.Struct {
-		return ErrInvalidDestType
+	items := make([]string, 0, len(values))
+	for _, value := range values {
+		split, err := splitList(value, f.Separator)
+		if err != nil {
+			return err
+		}
+		items = append(items, split...)
+	}
+	if f.Type.Kind() == reflect.Map {
+		return f.assignMap(field, items)
+	}
+	result := reflect.MakeSlice(f.Type, len(items), len(items))
+	for i, item := range items {
+		if err := f.setElem(result.Index(i), item); err != nil {
+			return err
+		}
+	}
+	field.Set(result)
+	return nil
+}
+
+// convert key=value items, and assign them to a map field
+func (f *FieldSpec) assignMap(field reflect.Value, items []string) error {
+	result := reflect.MakeMapWithSize(f.Type, len(items))
+	for _, item := range items {
+		k, v, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("invalid entry '%s', expected key=value", item)
+		}
+		key := reflect.New(f.Type.Key()).Elem()
+		if err := f.setKey(key, k); err != nil {
+			return &keyError{key: k, err: err}
+		}
+		if result.MapIndex(key).IsValid() {
+			switch f.DuplicateKeys {
+			case DuplicateKeysError:
+				return &keyError{key: k}
+			case DuplicateKeysFirst:
+				continue
+			}
+		}
+		value := reflect.New(f.Type.Elem()).Elem()
+		if err := f.setElem(value, v); err != nil {
+			return &keyError{key: k, err: err}
+		}
+		result.SetMapIndex(key, value)
+	}
+	field.Set(result)
+	return nil
+}
+
+// map a raw conversion error to a field error
+func (f *FieldSpec) wrapError(err error) error {
+	var keyErr *keyError
+	var choiceErr *choiceError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, errNotSupported):
+		return ErrNotSupported(f.Name)
+	case errors.As(err, &choiceErr):
+		result := ErrInvalidChoice(f.Name, err, choiceErr.choices)
+		if errors.As(err, &keyErr) {
+			result.Key = keyErr.key
+			result.FieldError = keyErr.err
+		}
+		return result
+	case errors.As(err, &keyErr):
+		if keyErr.err == nil {
+			return ErrDuplicateKey(f.Name, keyErr.key)
+		}
+		return ErrInvalidKeyValue(f.Name, keyErr.key, keyErr.err)
+	}
+	return ErrInvalidValue(f.Name, err)
+}
+
+// conversion error of a map entry; err is nil for duplicate keys
+type keyError struct {
+	key string
+	err error
+}
+
+func (e *keyError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("duplicate key '%s'", e.key)
 	}
+	return fmt.Sprintf("key '%s': %s", e.key, e.err.Error())
+}
+
+func (e *keyError) Unwrap() error {
+	return e.err
+}
+
+// converts a string value to the field type, and assigns it; returns the raw conversion error, if any
+type setter func(field reflect.Value, fValue string) error
+
+// per-field conversion options, from tag options
+type valueOptions struct {
+	layout     string         // layout, for time.Time values; empty for the default layouts
+	location   *time.Location // time zone, for time.Time values without zone
+	unit       string         // unit suffixes accepted by numeric values, UnitBytes or UnitSI; empty for none
+	ignoreCase bool           // enum names are matched case-insensitively
+}
 
-	for i := 0; i < t.NumField(); i++ {
-		field := v.Field(i)
-		kind := v.Field(i).Kind()
-		reserved := isReserved(field.Type().String())
-		if kind == reflect.Struct && !reserved {
-			if err := parseArgv(v.Field(i).Addr().Interface(), args); err != nil {
+// newSetter resolves the conversion for a given field type
+// Custom parsers take precedence, followed by ArgvUnmarshaler, encoding.TextUnmarshaler and flag.Value
+// implementations; other types are converted by kind, so named types such as "type Port uint16" are supported
+// as well.
+func (p *Parser) newSetter(t reflect.Type, opts valueOptions) setter {
+	if set, ok := p.typeParsers[t]; ok {
+		return set
+	}
+	if e, ok := p.enums[t]; ok {
+		return e.setter(opts.ignoreCase)
+	}
+	switch t {
+	case timeType:
+		return timeSetter(opts.layout, opts.location)
+	case durationType:
+		return durationSetter
+	case locationType:
+		return locationSetter
+	case reflect.PointerTo(timeType):
+		// *time.Time implements encoding.TextUnmarshaler, which would ignore the layout and time zone
+		return p.ptrSetter(t, opts)
+	}
+	fType := t.String()
+	if fn, ok := p.parsers[fType]; ok {
+		return func(field reflect.Value, fValue string) error {
+			v, err := fn(fValue)
+			if err != nil {
 				return err
 			}
-		} else {
-			fieldName, optional := parseTag(t.Field(i).Tag.Get(annotationTag))
-			if len(fieldName) == 0 {
-				continue
+			value := reflect.ValueOf(v)
+			if !value.IsValid() || !value.Type().AssignableTo(t) {
+				return fmt.Errorf("parser for %s returned %T", fType, v)
 			}
-			if !v.Field(i).CanInterface() {
-				continue
+			field.Set(value)
+			return nil
+		}
+	}
+	if isUnmarshaler(t) {
+		return func(field reflect.Value, fValue string) error {
+			target := field
+			if t.Kind() != reflect.Ptr {
+				target = field.Addr()
+			} else if field.IsNil() {
+				field.Set(reflect.New(t.Elem()))
 			}
-			// field has a tag, but it is not settable
-			if kind != reflect.Interface {
-				if !field.CanSet() {
-					return ErrReadOnly(fieldName)
-				}
+			return unmarshal(target.Interface(), fValue)
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return p.ptrSetter(t, opts)
+
+	case reflect.Bool:
+		return func(field reflect.Value, fValue string) error {
+			v, err := parseBool(fValue)
+			if err != nil {
+				return err
 			}
+			field.SetBool(v)
+			return nil
+		}
 
-			if fValue, ok := args[fieldName]; !ok {
-				if !optional {
-					return ErrMissingValue(fieldName)
-				}
-				continue
-			} else {
-				fType := field.Type().String()
-				switch fType {
-				case "time.Time":
-					v, err := mapTime(fValue)
-					if err != nil {
-						return ErrInvalidValue(fieldName, err)
-					}
-					field.Set(reflect.ValueOf(v))
-
-				case "bool":
-					v, err := parseBool(fValue)
-					if err != nil {
-						return ErrInvalidValue(fieldName, err)
-					}
-					field.SetBool(v)
-
-				case "byte", "uint8":
-					v, err := parseUint(fValue, 8)
-					if err != nil {
-						return ErrInvalidValue(fieldName, err)
-					}
-					field.SetUint(v)
-
-				case "int8":
-					v, err := parseInt(fValue, 8)
-					if err != nil {
-						return ErrInvalidValue(fieldName, err)
-					}
-					field.SetInt(v)
-				case "uint", "uint32":
-					v, err := parseUint(fValue, 32)
-					if err != nil {
-						return ErrInvalidValue(fieldName, err)
-					}
-					field.SetUint(v)
-
-				case "uint64":
-					v, err := parseUint(fValue, 64)
-					if err != nil {
-						return ErrInvalidValue(fieldName, err)
-					}
-					field.SetUint(v)
-
-				case "int", "int32":
-					v, err := parseInt(fValue, 32)
-					if err != nil {
-						return ErrInvalidValue(fieldName, err)
-					}
-					field.SetInt(v)
-				case "int64":
-					v, err := parseInt(fValue, 64)
-					if err != nil {
-						return ErrInvalidValue(fieldName, err)
-					}
-					field.SetInt(v)
-				case "float32":
-					v, err := parseFloat(fValue, 32)
-					if err != nil {
-						return ErrInvalidValue(fieldName, err)
-					}
-					field.SetFloat(v)
-
-				case "float64":
-					v, err := parseFloat(fValue, 64)
-					if err != nil {
-						return ErrInvalidValue(fieldName, err)
-					}
-					field.SetFloat(v)
-
-				case "string":
-					field.SetString(fValue)
-
-				case "[]string":
-					field.Set(reflect.ValueOf(parseStringArray(fValue)))
-				default:
-					if fn, ok := fieldParser[fType]; ok {
-						v, err := fn(fValue)
-						if err != nil {
-							return ErrInvalidValue(fieldName, err)
-						}
-						field.Set(reflect.ValueOf(v))
-					} else {
-						return ErrNotSupported(fieldName)
-					}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if len(opts.unit) > 0 {
+			return scaledIntSetter(opts.unit, t.Bits())
+		}
+		return intSetter(t.Bits())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if len(opts.unit) > 0 {
+			return scaledIntSetter(opts.unit, t.Bits())
+		}
+		return uintSetter(t.Bits())
+
+	case reflect.Float32, reflect.Float64:
+		if len(opts.unit) > 0 {
+			return scaledFloatSetter(opts.unit, t.Bits())
+		}
+		return floatSetter(t.Bits())
+
+	case reflect.Complex64, reflect.Complex128:
+		return complexSetter(t.Bits())
+
+	case reflect.String:
+		return func(field reflect.Value, fValue string) error {
+			field.SetString(fValue)
+			return nil
+		}
+
+	case reflect.Slice:
+		set := p.newSetter(t.Elem(), opts)
+		return func(field reflect.Value, fValue string) error {
+			items, err := splitList(fValue, defaultSeparator)
+			if err != nil {
+				return err
+			}
+			result := reflect.MakeSlice(t, len(items), len(items))
+			for i, item := range items {
+				if err := set(result.Index(i), item); err != nil {
+					return err
 				}
 			}
+			field.Set(result)
+			return nil
 		}
 	}
-	return nil
+	return func(field reflect.Value, fValue string) error {
+		return errNotSupported
+	}
+}
+
+// check if type, or a pointer to it, implements ArgvUnmarshaler, encoding.TextUnmarshaler or flag.Value
+func isUnmarshaler(t reflect.Type) bool {
+	if t.Kind() != reflect.Ptr {
+		t = reflect.PointerTo(t)
+	}
+	return t.Implements(argvUnmarshalerType) || t.Implements(textUnmarshalerType) || t.Implements(flagValueType)
+}
+
+// parse a value using the interfaces implemented by target, in order of precedence
+func unmarshal(target any, fValue string) error {
+	switch v := target.(type) {
+	case ArgvUnmarshaler:
+		return v.UnmarshalArgv(fValue)
+	case encoding.TextUnmarshaler:
+		return v.UnmarshalText([]byte(fValue))
+	case flag.Value:
+		return v.Set(fValue)
+	}
+	return errNotSupported
+}
+
+// setter for pointer fields; the value is allocated only when assigned
+func (p *Parser) ptrSetter(t reflect.Type, opts valueOptions) setter {
+	set := p.newSetter(t.Elem(), opts)
+	return func(field reflect.Value, fValue string) error {
+		value := reflect.New(t.Elem())
+		if err := set(value.Elem(), fValue); err != nil {
+			return err
+		}
+		field.Set(value)
+		return nil
+	}
+}
+
+// setter for a typed custom parser
+func typedSetter[T any](fn func(in string) (T, error)) setter {
+	return func(field reflect.Value, fValue string) error {
+		v, err := fn(fValue)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(&v).Elem())
+		return nil
+	}
+}
+
+// reflect.Type of T, including interface types
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func intSetter(size int) setter {
+	return func(field reflect.Value, fValue string) error {
+		v, err := parseInt(fValue, size)
+		if err != nil {
+			return err
+		}
+		field.SetInt(v)
+		return nil
+	}
+}
+
+func uintSetter(size int) setter {
+	return func(field reflect.Value, fValue string) error {
+		v, err := parseUint(fValue, size)
+		if err != nil {
+			return err
+		}
+		field.SetUint(v)
+		return nil
+	}
+}
+
+func floatSetter(size int) setter {
+	return func(field reflect.Value, fValue string) error {
+		v, err := parseFloat(fValue, size)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(v)
+		return nil
+	}
+}
+
+func complexSetter(size int) setter {
+	return func(field reflect.Value, fValue string) error {
+		v, err := parseComplex(fValue, size)
+		if err != nil {
+			return err
+		}
+		field.SetComplex(v)
+		return nil
+	}
 }
 
 func parseBool(in string) (bool, error) {
@@ -258,28 +623,114 @@ func parseBool(in string) (bool, error) {
 }
 
 func parseUint(in string, size int) (uint64, error) {
-	return strconv.ParseUint(in, 10, size)
+	literal, base := intLiteral(in)
+	v, err := strconv.ParseUint(literal, base, size)
+	return v, numError(err, in)
 }
 
 func parseInt(in string, size int) (int64, error) {
-	return strconv.ParseInt(in, 10, size)
+	literal, base := intLiteral(in)
+	v, err := strconv.ParseInt(literal, base, size)
+	return v, numError(err, in)
 }
 
-func mapTime(in string) (time.Time, error) {
-	return time.Parse(time.RFC3339, in)
+// intLiteral prepares an integer literal for strconv, returning its base
+// Literals with a 0x, 0o or 0b prefix are left to strconv; underscores are removed from decimal literals, which are
+// always parsed in base 10, so leading zeros do not denote octal numbers, unlike in Go.
+func intLiteral(in string) (string, int) {
+	digits := in
+	if len(digits) > 0 && (digits[0] == '+' || digits[0] == '-') {
+		digits = digits[1:]
+	}
+	if len(digits) > 2 && digits[0] == '0' && strings.ContainsRune("xXoObB", rune(digits[1])) {
+		return in, 0
+	}
+	if !strings.Contains(digits, "_") {
+		return in, 10
+	}
+	// underscores are only allowed between digits
+	for i := 0; i < len(digits); i++ {
+		if digits[i] == '_' && (i == 0 || i == len(digits)-1 || !isDigit(digits[i-1]) || !isDigit(digits[i+1])) {
+			return in, 10
+		}
+	}
+	return strings.ReplaceAll(in, "_", ""), 10
+}
+
+// report the original value in strconv errors
+func numError(err error, in string) error {
+	if numErr, ok := err.(*strconv.NumError); ok {
+		numErr.Num = in
+	}
+	return err
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
 }
 
 func parseFloat(in string, size int) (float64, error) {
 	return strconv.ParseFloat(in, size)
 }
 
-func parseStringArray(in string) []string {
+func parseComplex(in string, size int) (complex128, error) {
+	return strconv.ParseComplex(in, size)
+}
+
+// splitList splits a list of values, such as "a,b,c"; with an empty separator, the value is not split
+// Items may be double-quoted to include the separator, e.g. `"a,b",c`; inside quotes, "" is a literal quote.
+// Whitespace around items is removed, unless quoted.
+func splitList(in string, sep string) ([]string, error) {
 	result := make([]string, 0)
 	if len(in) == 0 {
-		return result
+		return result, nil
+	}
+	if len(sep) == 0 {
+		return append(result, in), nil
+	}
+	trim := func(s string) string {
+		if len(strings.TrimSpace(sep)) == 0 {
+			return s
+		}
+		return strings.TrimLeft(s, " \t")
 	}
-	for _, v := range strings.Split(in, ",") {
-		result = append(result, strings.TrimSpace(v))
+	pos := 0
+	for {
+		rest := trim(in[pos:])
+		if !strings.HasPrefix(rest, `"`) {
+			idx := strings.Index(in[pos:], sep)
+			if idx < 0 {
+				return append(result, strings.TrimSpace(in[pos:])), nil
+			}
+			result = append(result, strings.TrimSpace(in[pos:pos+idx]))
+			pos += idx + len(sep)
+			continue
+		}
+
+		// quoted item
+		pos = len(in) - len(rest) + 1
+		var item strings.Builder
+		for {
+			idx := strings.IndexByte(in[pos:], '"')
+			if idx < 0 {
+				return nil, fmt.Errorf("unterminated quote")
+			}
+			item.WriteString(in[pos : pos+idx])
+			pos += idx + 1
+			if !strings.HasPrefix(in[pos:], `"`) {
+				break
+			}
+			item.WriteByte('"')
+			pos++
+		}
+		result = append(result, item.String())
+		pos = len(in) - len(trim(in[pos:]))
+		if pos == len(in) {
+			return result, nil
+		}
+		if !strings.HasPrefix(in[pos:], sep) {
+			return nil, fmt.Errorf("unexpected character after quoted value at position %d", pos)
+		}
+		pos += len(sep)
 	}
-	return result
 }
@@ -3,6 +3,12 @@ package argv
 import (
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"math"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -49,6 +55,11 @@ func TestParseArgvInitErrors(t *testing.T) {
 	err := ParseArgv(dest, payload)
 	assert.ErrorIs(t, ErrEmptyArgs, err)
 
+	// empty payload, with required args supplied by environment variables
+	env := WithEnvLookup(envMap(map[string]string{"CERT_ARG1": "1", "CERT_ARG2": "2", "CERT_ARG3": "3", "CERT_ARG4": "4"}))
+	assert.Nil(t, ParseArgv(dest, payload, WithEnvPrefix("CERT_"), env))
+	assert.Equal(t, &ArgStructInt{Arg1: 1, Arg2: 2, Arg3: 3, Arg4: 4}, dest)
+
 	// odd arg count, should return ErrEmptyArgs
 	payload = []string{"param1", "value1", "param2"}
 	err = ParseArgv(dest, payload)
@@ -302,9 +313,10 @@ func TestParseArgvTime(t *testing.T) {
 		expectedValues ArgStructTime
 	}{
 		{
+			// unknown args are reported before missing ones, as they are likely misspelled
 			name:           "missing arg1",
 			args:           []string{"arg2", "2.0"},
-			expected:       ErrMissingValue("arg1"),
+			expected:       ErrUnknownArgs([]string{"arg2"}, map[string][]string{"arg2": {"arg1"}}),
 			expectedValues: ArgStructTime{},
 		},
 		{
@@ -464,3 +476,750 @@ func TestParseArgvString(t *testing.T) {
 		})
 	}
 }
+
+type argPort uint16
+type argMode string
+type argRatio float32
+type argSwitch bool
+type argTags []string
+
+type ArgStructKinds struct {
+	Port    argPort    `argv:"port,optional"`
+	Mode    argMode    `argv:"mode,optional"`
+	Ratio   argRatio   `argv:"ratio,optional"`
+	Enabled argSwitch  `argv:"enabled,optional"`
+	Tags    argTags    `argv:"tags,optional"`
+	Modes   []argMode  `argv:"modes,optional"`
+	Int16   int16      `argv:"int16,optional"`
+	Int     int        `argv:"int,optional"`
+	Uint    uint       `argv:"uint,optional"`
+	Ptr     uintptr    `argv:"ptr,optional"`
+	C64     complex64  `argv:"c64,optional"`
+	C128    complex128 `argv:"c128,optional"`
+}
+
+func TestParseArgvKinds(t *testing.T) {
+	testCases := []struct {
+		name           string
+		args           []string
+		expected       error
+		expectedValues ArgStructKinds
+	}{
+		{
+			name: "named types",
+			args: []string{"--port", "8080", "--mode", "fast", "--ratio", "0.5", "--enabled", "--tags", "a, b", "--modes", "x,y"},
+			expectedValues: ArgStructKinds{
+				Port:    8080,
+				Mode:    "fast",
+				Ratio:   0.5,
+				Enabled: true,
+				Tags:    argTags{"a", "b"},
+				Modes:   []argMode{"x", "y"},
+			},
+		},
+		{
+			name: "other kinds",
+			args: []string{"--int16", "-32768", "--ptr", "4096", "--c64", "1+2i", "--c128", "(-0.5-3i)"},
+			expectedValues: ArgStructKinds{
+				Int16: -32768,
+				Ptr:   4096,
+				C64:   complex(1, 2),
+				C128:  complex(-0.5, -3),
+			},
+		},
+		{
+			// int and uint use the platform size
+			name:           "platform int size",
+			args:           []string{"--int", strconv.Itoa(math.MinInt), "--uint", strconv.FormatUint(uint64(^uint(0)), 10)},
+			expectedValues: ArgStructKinds{Int: math.MinInt, Uint: ^uint(0)},
+		},
+		{
+			name:           "integer literals",
+			args:           []string{"--int16", "-0x1F", "--port", "0o755", "--uint", "0b1010", "--int", "1_000_000"},
+			expectedValues: ArgStructKinds{Int16: -31, Port: 493, Uint: 10, Int: 1000000},
+		},
+		{
+			// unlike Go, leading zeros do not denote octal numbers
+			name:           "leading zeros",
+			args:           []string{"--port", "0755", "--int", "-0_10"},
+			expectedValues: ArgStructKinds{Port: 755, Int: -10},
+		},
+		{
+			name:     "invalid underscores",
+			args:     []string{"--int", "1__000"},
+			expected: fmt.Errorf("error parsing arg int: strconv.ParseInt: parsing \"1__000\": invalid syntax"),
+		},
+		{
+			name:     "overflow named type",
+			args:     []string{"--port", "65536"},
+			expected: fmt.Errorf("error parsing arg port: strconv.ParseUint: parsing \"65536\": value out of range"),
+		},
+		{
+			name:     "overflow int16",
+			args:     []string{"--int16", "40000"},
+			expected: fmt.Errorf("error parsing arg int16: strconv.ParseInt: parsing \"40000\": value out of range"),
+		},
+		{
+			name:     "invalid complex",
+			args:     []string{"--c64", "1+x"},
+			expected: fmt.Errorf("error parsing arg c64: strconv.ParseComplex: parsing \"1+x\": invalid syntax"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dest := &ArgStructKinds{}
+			err := ParseArgv(dest, tc.args)
+			if tc.expected != nil {
+				assert.EqualError(t, err, tc.expected.Error())
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, &tc.expectedValues, dest)
+		})
+	}
+}
+
+// implements both ArgvUnmarshaler and encoding.TextUnmarshaler
+type argID struct {
+	Source string
+	Value  string
+}
+
+func (i *argID) UnmarshalArgv(value string) error {
+	if len(value) == 0 {
+		return fmt.Errorf("empty id")
+	}
+	*i = argID{Source: "argv", Value: value}
+	return nil
+}
+
+func (i *argID) UnmarshalText(text []byte) error {
+	*i = argID{Source: "text", Value: string(text)}
+	return nil
+}
+
+// implements flag.Value
+type argVerbosity int
+
+func (v *argVerbosity) String() string {
+	return strconv.Itoa(int(*v))
+}
+
+func (v *argVerbosity) Set(value string) error {
+	*v = argVerbosity(strings.Count(value, "v"))
+	return nil
+}
+
+type ArgStructUnmarshal struct {
+	ID        argID        `argv:"id,optional"`
+	IP        net.IP       `argv:"ip,optional"`
+	Big       big.Int      `argv:"big,optional"`
+	BigPtr    *big.Int     `argv:"big-ptr,optional"`
+	Verbosity argVerbosity `argv:"verbosity,optional"`
+	IDs       []argID      `argv:"#rest,optional"`
+}
+
+func TestParseArgvUnmarshal(t *testing.T) {
+	dest := &ArgStructUnmarshal{}
+	err := ParseArgv(dest, []string{"--id", "a1", "--ip", "10.0.0.1", "--big", "123456789012345678901234567890",
+		"--big-ptr", "-42", "--verbosity", "vvv", "b2", "c3"})
+	assert.Nil(t, err)
+	assert.Equal(t, argID{Source: "argv", Value: "a1"}, dest.ID)
+	assert.Equal(t, net.ParseIP("10.0.0.1"), dest.IP)
+	assert.Equal(t, "123456789012345678901234567890", dest.Big.String())
+	assert.Equal(t, big.NewInt(-42), dest.BigPtr)
+	assert.Equal(t, argVerbosity(3), dest.Verbosity)
+	assert.Equal(t, []argID{{Source: "argv", Value: "b2"}, {Source: "argv", Value: "c3"}}, dest.IDs)
+
+	// struct types parsing their own values are not recursively parsed
+	names, err := ParseNames(&ArgStructUnmarshal{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "ip", "big", "big-ptr", "verbosity"}, names)
+
+	err = ParseArgv(&ArgStructUnmarshal{}, []string{"--id="})
+	assert.Equal(t, ErrInvalidValue("id", fmt.Errorf("empty id")), err)
+	err = ParseArgv(&ArgStructUnmarshal{}, []string{"--ip", "10.0.0"})
+	assert.EqualError(t, err, "error parsing arg ip: invalid IP address: 10.0.0")
+}
+
+type ArgStructSlices struct {
+	Tags    []string  `argv:"tag|t,optional"`
+	Ports   []int     `argv:"port,optional,sep=':'"`
+	IPs     []net.IP  `argv:"ip,optional"`
+	Modes   []argMode `argv:"mode,optional,sep="`
+	IDs     []argID   `argv:"id,optional"`
+	Weights []float64 `argv:"weight,optional,default='0.5, 1.5'"`
+	Files   []int     `argv:"#rest,optional"`
+}
+
+type ArgStructInvalidSep struct {
+	Name string `argv:"name,sep=';'"`
+}
+
+func TestParseArgvSlices(t *testing.T) {
+	testCases := []struct {
+		name           string
+		args           []string
+		expected       error
+		expectedValues ArgStructSlices
+	}{
+		{
+			name: "repeated args",
+			args: []string{"--tag", "a", "-t", "b,c", "--port", "80", "--port", "443:8080", "--ip", "10.0.0.1",
+				"--ip", "::1", "--", "1", "2"},
+			expectedValues: ArgStructSlices{
+				Tags:    []string{"a", "b", "c"},
+				Ports:   []int{80, 443, 8080},
+				IPs:     []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("::1")},
+				Weights: []float64{0.5, 1.5},
+				Files:   []int{1, 2},
+			},
+		},
+		{
+			name: "quoted items",
+			args: []string{"--tag", `"a,b", c ,"say ""hi"""`, "--tag="},
+			expectedValues: ArgStructSlices{
+				Tags:    []string{"a,b", "c", `say "hi"`},
+				Weights: []float64{0.5, 1.5},
+			},
+		},
+		{
+			name: "no separator",
+			args: []string{"--mode", "a,b", "--mode", "c", "--id", "x,y", "--weight", "2"},
+			expectedValues: ArgStructSlices{
+				Modes:   []argMode{"a,b", "c"},
+				IDs:     []argID{{Source: "argv", Value: "x"}, {Source: "argv", Value: "y"}},
+				Weights: []float64{2},
+			},
+		},
+		{
+			name:     "invalid item",
+			args:     []string{"--port", "80:http"},
+			expected: fmt.Errorf("error parsing arg port: strconv.ParseInt: parsing \"http\": invalid syntax"),
+		},
+		{
+			name:     "unterminated quote",
+			args:     []string{"--tag", `"a,b`},
+			expected: fmt.Errorf("error parsing arg tag: unterminated quote"),
+		},
+		{
+			name:     "text after quote",
+			args:     []string{"--tag", `"a"b,c`},
+			expected: fmt.Errorf("error parsing arg tag: unexpected character after quoted value at position 3"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dest := &ArgStructSlices{}
+			err := ParseArgv(dest, tc.args)
+			if tc.expected != nil {
+				assert.EqualError(t, err, tc.expected.Error())
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, &tc.expectedValues, dest)
+		})
+	}
+
+	err := ParseArgv(&ArgStructInvalidSep{}, []string{"--name", "x"})
+	assert.EqualError(t, err, "invalid argv tag on field ArgStructInvalidSep.Name: sep requires a slice or map field")
+}
+
+type ArgStructMaps struct {
+	Labels  map[string]string `argv:"label|l,optional"`
+	Limits  map[string]int    `argv:"limit,optional,dupkeys=error"`
+	Weights map[int]float64   `argv:"weight,optional,dupkeys=first,sep=';'"`
+	IDs     map[argMode]argID `argv:"id,optional"`
+	Hosts   map[string]net.IP `argv:"host,optional,default='local=127.0.0.1'"`
+}
+
+type ArgStructInvalidDupKeys struct {
+	Labels map[string]string `argv:"label,dupkeys=never"`
+}
+
+func TestParseArgvMaps(t *testing.T) {
+	testCases := []struct {
+		name           string
+		args           []string
+		expected       error
+		expectedValues ArgStructMaps
+	}{
+		{
+			name: "repeated and split",
+			args: []string{"--label", "env=prod", "-l", "tier=web,team=ops", "--label", "env=dev", "--limit", "cpu=2,mem=512",
+				"--weight", "1=0.5;2=1.5;1=3", "--id", "a=x,b=y", "--host", "db=10.0.0.2"},
+			expectedValues: ArgStructMaps{
+				Labels:  map[string]string{"env": "dev", "tier": "web", "team": "ops"},
+				Limits:  map[string]int{"cpu": 2, "mem": 512},
+				Weights: map[int]float64{1: 0.5, 2: 1.5},
+				IDs:     map[argMode]argID{"a": {Source: "argv", Value: "x"}, "b": {Source: "argv", Value: "y"}},
+				Hosts:   map[string]net.IP{"db": net.ParseIP("10.0.0.2")},
+			},
+		},
+		{
+			name: "quoted and empty values",
+			args: []string{"--label", `"names=a,b",empty=`},
+			expectedValues: ArgStructMaps{
+				Labels: map[string]string{"names": "a,b", "empty": ""},
+				Hosts:  map[string]net.IP{"local": net.ParseIP("127.0.0.1")},
+			},
+		},
+		{
+			name:     "missing separator",
+			args:     []string{"--label", "env"},
+			expected: ErrInvalidValue("label", fmt.Errorf("invalid entry 'env', expected key=value")),
+		},
+		{
+			name:     "invalid value",
+			args:     []string{"--limit", "cpu=2,mem=lots"},
+			expected: ErrInvalidKeyValue("limit", "mem", fmt.Errorf("strconv.ParseInt: parsing \"lots\": invalid syntax")),
+		},
+		{
+			name:     "invalid key",
+			args:     []string{"--weight", "one=1"},
+			expected: ErrInvalidKeyValue("weight", "one", fmt.Errorf("strconv.ParseInt: parsing \"one\": invalid syntax")),
+		},
+		{
+			name:     "duplicate key",
+			args:     []string{"--limit", "cpu=2", "--limit", "cpu=4"},
+			expected: ErrDuplicateKey("limit", "cpu"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dest := &ArgStructMaps{}
+			err := ParseArgv(dest, tc.args)
+			if tc.expected != nil {
+				assert.EqualError(t, err, tc.expected.Error())
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, &tc.expectedValues, dest)
+		})
+	}
+
+	err := ParseArgv(&ArgStructMaps{}, []string{"--limit", "cpu=x"})
+	assert.Equal(t, "cpu", err.(FieldError).Key)
+	assert.EqualError(t, err, "error parsing arg limit, key 'cpu': strconv.ParseInt: parsing \"x\": invalid syntax")
+	assert.EqualError(t, ErrDuplicateKey("limit", "cpu"), "duplicate key 'cpu' for arg limit")
+
+	err = ParseArgv(&ArgStructInvalidDupKeys{}, []string{"--label", "a=b"})
+	assert.EqualError(t, err, "invalid argv tag on field ArgStructInvalidDupKeys.Labels: invalid dupkeys policy 'never'")
+}
+
+type ArgStructPositional struct {
+	Domain string   `argv:"#0"`
+	Output string   `argv:"#1,optional"`
+	Days   int      `argv:"days,optional"`
+	Rest   []string `argv:"#rest,optional"`
+}
+
+type ArgStructPositionalTyped struct {
+	Verbose bool  `argv:"verbose,optional"`
+	Values  []int `argv:"#rest"`
+}
+
+type ArgStructPositionalGap struct {
+	Arg1 string `argv:"#0"`
+	Arg2 string `argv:"#2"`
+}
+
+type ArgStructPositionalOrder struct {
+	Arg1 string `argv:"#0,optional"`
+	Arg2 string `argv:"#1"`
+}
+
+type ArgStructPositionalRest struct {
+	Arg1 string `argv:"#rest"`
+}
+
+func TestParseArgvPositional(t *testing.T) {
+	testCases := []struct {
+		name           string
+		args           []string
+		expected       error
+		expectedValues ArgStructPositional
+	}{
+		{
+			name:           "missing #0",
+			args:           []string{"-days", "30"},
+			expected:       ErrMissingPositional("<domain>"),
+			expectedValues: ArgStructPositional{},
+		},
+		{
+			name:     "required only",
+			args:     []string{"example.com"},
+			expected: nil,
+			expectedValues: ArgStructPositional{
+				Domain: "example.com",
+			},
+		},
+		{
+			name:     "mixed with named args",
+			args:     []string{"example.com", "-days", "30", "out.pem"},
+			expected: nil,
+			expectedValues: ArgStructPositional{
+				Domain: "example.com",
+				Output: "out.pem",
+				Days:   30,
+			},
+		},
+		{
+			// a lone dash is a value, usually meaning stdin
+			name:     "dash",
+			args:     []string{"example.com", "-", "-days", "30"},
+			expected: nil,
+			expectedValues: ArgStructPositional{
+				Domain: "example.com",
+				Output: "-",
+				Days:   30,
+			},
+		},
+		{
+			name:     "rest values",
+			args:     []string{"example.com", "out.pem", "a", "--", "-b"},
+			expected: nil,
+			expectedValues: ArgStructPositional{
+				Domain: "example.com",
+				Output: "out.pem",
+				Rest:   []string{"a", "-b"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dest := &ArgStructPositional{}
+			err := ParseArgv(dest, tc.args)
+			if tc.expected != nil {
+				assert.Equal(t, tc.expected, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, &tc.expectedValues, dest)
+		})
+	}
+}
+
+func TestParseArgvPositionalTyped(t *testing.T) {
+	dest := &ArgStructPositionalTyped{}
+	assert.Nil(t, ParseArgv(dest, []string{"1", "-verbose", "2", "3"}))
+	assert.Equal(t, &ArgStructPositionalTyped{Verbose: true, Values: []int{1, 2, 3}}, dest)
+
+	// boolean literals after a flag are positional values; explicit flag values must be inline
+	dest = &ArgStructPositionalTyped{}
+	assert.Nil(t, ParseArgv(dest, []string{"-verbose", "1", "2", "3"}))
+	assert.Equal(t, &ArgStructPositionalTyped{Verbose: true, Values: []int{1, 2, 3}}, dest)
+
+	dest = &ArgStructPositionalTyped{}
+	assert.Nil(t, ParseArgv(dest, []string{"-verbose=0", "1"}))
+	assert.Equal(t, &ArgStructPositionalTyped{Verbose: false, Values: []int{1}}, dest)
+
+	dest = &ArgStructPositionalTyped{}
+	err := ParseArgv(dest, []string{"1", "x"})
+	assert.EqualError(t, err, "error parsing arg #rest: strconv.ParseInt: parsing \"x\": invalid syntax")
+
+	dest = &ArgStructPositionalTyped{}
+	err = ParseArgv(dest, []string{"-verbose"})
+	assert.Equal(t, ErrMissingPositional("<values>"), err)
+
+	// positional args are named as in usage
+	err = ParseArgv(&ArgStructPositional{}, []string{"-days", "30"})
+	assert.EqualError(t, err, "value for positional arg <domain> is missing")
+}
+
+func TestParseArgvPositionalErrors(t *testing.T) {
+	err := ParseArgv(&ArgStructPositionalGap{}, []string{"a"})
+	assert.EqualError(t, err, "invalid argv tag on field ArgStructPositionalGap.Arg2: positional slots must be contiguous, starting at #0")
+
+	err = ParseArgv(&ArgStructPositionalOrder{}, []string{"a"})
+	assert.EqualError(t, err, "invalid argv tag on field ArgStructPositionalOrder.Arg2: required positional slot after optional slot")
+
+	err = ParseArgv(&ArgStructPositionalRest{}, []string{"a"})
+	assert.EqualError(t, err, "invalid argv tag on field ArgStructPositionalRest.Arg1: #rest requires a slice field")
+
+	// extra positional values, without a #rest field
+	err = ParseArgv(&ArgStructTime{}, []string{"arg1", "2006-01-02T15:04:05Z", "--", "extra"})
+	assert.Equal(t, ErrUnexpectedArg("extra"), err)
+}
+
+type ArgStructExtraMap struct {
+	Days  int               `argv:"days"`
+	Extra map[string]string `argv:"#extra"`
+}
+
+type ArgStructExtraList struct {
+	Days  int      `argv:"days"`
+	Extra []string `argv:"#extra"`
+}
+
+func TestParseArgvStrict(t *testing.T) {
+	// strict mode is the default
+	err := ParseArgv(&ArgStructInt{}, []string{"arg1", "1", "arg2", "2", "arg3", "3", "arg4", "4", "-arg5", "5"})
+	assert.Equal(t, ErrUnknownArgs([]string{"arg5"}, map[string][]string{"arg5": {"arg1", "arg2", "arg3"}}), err)
+	assert.EqualError(t, err, "unknown arg 'arg5'; did you mean 'arg1', 'arg2' or 'arg3'?")
+
+	err = ParseArgv(&ArgStructInt{}, []string{"-dasy", "30", "arg1", "1", "arg2", "2", "arg3", "3", "arg4", "4", "--quiet"})
+	assert.EqualError(t, err, "unknown args 'dasy', 'quiet'")
+
+	// a misspelled required arg is reported as unknown, not as missing
+	err = ParseArgv(&ArgStructTime{}, []string{"-arg", "2024-03-01T00:00:00Z"}, WithStrict(true))
+	assert.Equal(t, ErrUnknownArgs([]string{"arg"}, map[string][]string{"arg": {"arg1"}}), err)
+	err = ParseArgv(&ArgStructInt{}, []string{"arg1", "1", "-arg22", "2", "arg3", "3", "arg4", "4"})
+	assert.EqualError(t, err, "unknown arg 'arg22'; did you mean 'arg2', 'arg1' or 'arg3'?")
+
+	// permissive mode ignores unknown args
+	dest := &ArgStructInt{}
+	err = ParseArgv(dest, []string{"-dasy", "30", "arg1", "1", "arg2", "2", "arg3", "3", "arg4", "4"}, WithStrict(false))
+	assert.Nil(t, err)
+	assert.Equal(t, &ArgStructInt{Arg1: 1, Arg2: 2, Arg3: 3, Arg4: 4}, dest)
+}
+
+func TestParseArgvExtra(t *testing.T) {
+	destMap := &ArgStructExtraMap{}
+	err := ParseArgv(destMap, []string{"-days", "30", "--other=x", "-flag", "-more", "y"})
+	assert.Nil(t, err)
+	assert.Equal(t, &ArgStructExtraMap{
+		Days:  30,
+		Extra: map[string]string{"other": "x", "flag": "", "more": "y"},
+	}, destMap)
+
+	destList := &ArgStructExtraList{}
+	err = ParseArgv(destList, []string{"-days", "30", "--other=x", "-flag", "-more", "y"}, WithStrict(true))
+	assert.Nil(t, err)
+	assert.Equal(t, &ArgStructExtraList{
+		Days:  30,
+		Extra: []string{"--other=x", "-flag", "-more", "y"},
+	}, destList)
+}
+
+type testLevel int
+
+type ArgStructDefaults struct {
+	Name   string    `argv:"#0"`
+	Output string    `argv:"#1,default=out.pem"`
+	Days   uint32    `argv:"days,default=365"`
+	Tags   []string  `argv:"tags,optional,default=a"`
+	Level  testLevel `argv:"level,default=high"`
+}
+
+type ArgStructOnlyDefaults struct {
+	Days    uint32 `argv:"days,default=365"`
+	Verbose bool   `argv:"verbose,optional"`
+}
+
+type ArgStructInvalidDefault struct {
+	Days uint32 `argv:"days,default=never"`
+}
+
+func TestParseArgvDefaults(t *testing.T) {
+	AddParser("argv.testLevel", func(in string) (any, error) {
+		switch in {
+		case "low":
+			return testLevel(1), nil
+		case "high":
+			return testLevel(2), nil
+		}
+		return nil, fmt.Errorf("invalid level '%s'", in)
+	})
+
+	dest := &ArgStructDefaults{}
+	assert.Nil(t, ParseArgv(dest, []string{"example.com"}))
+	assert.Equal(t, &ArgStructDefaults{
+		Name:   "example.com",
+		Output: "out.pem",
+		Days:   365,
+		Tags:   []string{"a"},
+		Level:  testLevel(2),
+	}, dest)
+
+	dest = &ArgStructDefaults{}
+	assert.Nil(t, ParseArgv(dest, []string{"example.com", "cert.pem", "-days", "30", "-level", "low"}))
+	assert.Equal(t, &ArgStructDefaults{
+		Name:   "example.com",
+		Output: "cert.pem",
+		Days:   30,
+		Tags:   []string{"a"},
+		Level:  testLevel(1),
+	}, dest)
+
+	// defaults apply to a bare invocation, without args
+	onlyDefaults := &ArgStructOnlyDefaults{}
+	result, err := Parse(onlyDefaults, []string{})
+	assert.Nil(t, err)
+	assert.Equal(t, &ArgStructOnlyDefaults{Days: 365}, onlyDefaults)
+	assert.Equal(t, SourceDefault, result.Source("Days"))
+
+	// invalid defaults are reported with the schema, even if the arg is supplied
+	expected := "invalid argv tag on field ArgStructInvalidDefault.Days: invalid default value: strconv.ParseUint: parsing \"never\": invalid syntax"
+	err = ParseArgv(&ArgStructInvalidDefault{}, []string{"-days", "1"})
+	assert.EqualError(t, err, expected)
+	_, err = ParseSchema(&ArgStructInvalidDefault{})
+	assert.EqualError(t, err, expected)
+	_, err = Usage("gencert", &ArgStructInvalidDefault{})
+	assert.EqualError(t, err, expected)
+
+	defaults, err := ParseDefaults(&ArgStructDefaults{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"#1": "out.pem", "days": "365", "tags": "a", "level": "high"}, defaults)
+}
+
+type ArgStructAliases struct {
+	Days    uint32 `argv:"d|days,optional"`
+	Verbose bool   `argv:"verbose|v,optional"`
+	Nested  struct {
+		Output string `argv:"output|o|out,optional"`
+	}
+}
+
+type ArgStructDuplicateAlias struct {
+	Days   uint32 `argv:"days|d"`
+	Nested struct {
+		Debug bool `argv:"debug|d"`
+	}
+}
+
+func TestParseArgvAliases(t *testing.T) {
+	testCases := []struct {
+		name           string
+		args           []string
+		expected       string
+		expectedValues ArgStructAliases
+	}{
+		{
+			name: "long names",
+			args: []string{"--days", "30", "--verbose", "--output=x"},
+			expectedValues: ArgStructAliases{
+				Days:    30,
+				Verbose: true,
+				Nested: struct {
+					Output string `argv:"output|o|out,optional"`
+				}{Output: "x"},
+			},
+		},
+		{
+			name: "short names",
+			args: []string{"-d", "30", "-v", "-o", "x"},
+			expectedValues: ArgStructAliases{
+				Days:    30,
+				Verbose: true,
+				Nested: struct {
+					Output string `argv:"output|o|out,optional"`
+				}{Output: "x"},
+			},
+		},
+		{
+			name: "legacy single dash long names",
+			args: []string{"-days", "30", "-out", "x"},
+			expectedValues: ArgStructAliases{
+				Days: 30,
+				Nested: struct {
+					Output string `argv:"output|o|out,optional"`
+				}{Output: "x"},
+			},
+		},
+		{
+			name:     "short names require single dash",
+			args:     []string{"--d", "30"},
+			expected: "unknown arg 'd'",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dest := &ArgStructAliases{}
+			err := ParseArgv(dest, tc.args)
+			if len(tc.expected) > 0 {
+				assert.EqualError(t, err, tc.expected)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, &tc.expectedValues, dest)
+		})
+	}
+
+	names, err := ParseNames(&ArgStructAliases{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"d|days", "verbose|v", "output|o|out"}, names)
+
+	_, err = ParseNames(&ArgStructDuplicateAlias{})
+	assert.EqualError(t, err, "invalid argv tag on field ArgStructDuplicateAlias.Nested.Debug: duplicate argument name 'd', also used by ArgStructDuplicateAlias.Days")
+}
+
+type BenchAlgorithm struct {
+	Algorithm string `argv:"alg|a,default=rsa"`
+	KeyLen    uint   `argv:"bits|b,default=2048"`
+}
+
+type BenchJobSpec struct {
+	Name      string  `argv:"name|n"`
+	Days      uint32  `argv:"days|d,optional,env=BENCH_DAYS"`
+	Verbose   int     `argv:"verbose|v,counter,optional"`
+	Force     bool    `argv:"force|f,optional"`
+	Timeout   float64 `argv:"timeout,default=1.5"`
+	Algorithm BenchAlgorithm
+	Files     []string `argv:"#rest,optional"`
+}
+
+var benchArgv = []string{"--name", "job", "-d", "30", "-vvf", "--bits=4096", "--", "a.txt", "b.txt"}
+
+func BenchmarkParseArgv(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var dest BenchJobSpec
+		if err := ParseArgv(&dest, benchArgv); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// baseline, compiling the schema on every call
+func BenchmarkParseArgvUncached(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		defaultParser.resetCache()
+		var dest BenchJobSpec
+		if err := ParseArgv(&dest, benchArgv); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseArgvParallel(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			var dest BenchJobSpec
+			if err := ParseArgv(&dest, benchArgv); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkParseNames(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseNames(&BenchJobSpec{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseArgvConcurrent(t *testing.T) {
+	defaultParser.resetCache()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var dest BenchJobSpec
+			assert.Nil(t, ParseArgv(&dest, benchArgv))
+			assert.Equal(t, uint(4096), dest.Algorithm.KeyLen)
+			assert.Equal(t, []string{"a.txt", "b.txt"}, dest.Files)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,218 @@
+package argv
+
+import (
+	"fmt"
+	"io"
+)
+
+// RunFunc is invoked after the arguments of a command are parsed
+type RunFunc func() error
+
+// Command is a named command, bound to a destination struct, with optional subcommands
+//
+// Named args of a command are global to its subcommands: they can be used either before or after the
+// subcommand name, e.g. "tool -v gencert example.com" or "tool gencert example.com -v".
+type Command struct {
+	name        string
+	dest        any
+	run         RunFunc
+	description string
+	parent      *Command
+	commands    []*Command
+	parser      *Parser
+}
+
+// NewCommand creates a new command; dest may be nil if the command has no arguments, and run may be nil
+// if the command requires a subcommand
+func NewCommand(name string, dest any, run RunFunc) *Command {
+	return &Command{
+		name:     name,
+		dest:     dest,
+		run:      run,
+		commands: make([]*Command, 0),
+	}
+}
+
+// Name returns the command name
+func (c *Command) Name() string {
+	return c.name
+}
+
+// Path returns the full command name, including parent commands, e.g. "tool cert gen"
+func (c *Command) Path() string {
+	if c.parent == nil {
+		return c.name
+	}
+	return c.parent.Path() + " " + c.name
+}
+
+// WithDescription sets the command description, shown in usage output
+func (c *Command) WithDescription(description string) *Command {
+	c.description = description
+	return c
+}
+
+// WithParser sets the parser used for the command tree; only applies to the root command
+// Defaults to the parser used by the top-level functions, such as ParseArgv.
+func (c *Command) WithParser(p *Parser) *Command {
+	c.parser = p
+	return c
+}
+
+// parser of the command tree
+func (c *Command) parserOf() *Parser {
+	root := c
+	for root.parent != nil {
+		root = root.parent
+	}
+	if root.parser == nil {
+		return defaultParser
+	}
+	return root.parser
+}
+
+// AddCommand registers subcommands; command names must be unique within the same parent
+// As with duplicate flag definitions in the standard flag package, registering a duplicate name panics.
+func (c *Command) AddCommand(cmds ...*Command) *Command {
+	for _, cmd := range cmds {
+		if c.find(cmd.name) != nil {
+			panic(fmt.Sprintf("argv: duplicate command '%s' in %s", cmd.name, c.Path()))
+		}
+		cmd.parent = c
+		c.commands = append(c.commands, cmd)
+	}
+	return c
+}
+
+// Commands returns the registered subcommands
+func (c *Command) Commands() []*Command {
+	return c.commands
+}
+
+// find a subcommand by name
+func (c *Command) find(name string) *Command {
+	for _, cmd := range c.commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// build command schema, with the parent schema providing global args
+func (c *Command) schema(p *Parser, parent *Schema) (*Schema, error) {
+	s := emptySchema()
+	if c.dest != nil {
+		var err error
+		if s, err = p.schema(c.dest); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.commands) > 0 && s.hasPositional() {
+		return nil, ErrPositionalCommand
+	}
+	return s.withParent(parent), nil
+}
+
+// Execute parses args, usually os.Args[1:], and dispatches them to the matching command
+//
+// The arguments of each command in the path are parsed into its destination struct, starting from the root,
+// and the run function of the last command is invoked. If -h or --help is present, the usage of the last command
+// is written to the configured output (see WithOutput), and ErrHelp is returned.
+func (c *Command) Execute(args []string, opts ...Option) error {
+	p := c.parserOf()
+	cfg := p.config(opts)
+
+	// resolve command path, tokenizing the arguments of each command
+	cmd := c
+	path := make([]*Command, 0)
+	schemas := make([]*Schema, 0)
+	tokens := make(map[*Schema][]token, 0)
+	positional := make([]string, 0)
+	var parent *Schema
+	for {
+		s, err := cmd.schema(p, parent)
+		if err != nil {
+			return err
+		}
+		path = append(path, cmd)
+		schemas = append(schemas, s)
+		parent = s
+
+		stopAtCommand := len(cmd.commands) > 0
+		toks, values, next, err := tokenize(args, s, stopAtCommand)
+		if err != nil {
+			return err
+		}
+		// global args are parsed by the command declaring them
+		for _, tok := range toks {
+			owner := s
+			if tok.spec != nil {
+				owner = s.owner(tok.spec)
+			}
+			tokens[owner] = append(tokens[owner], tok)
+		}
+		if !stopAtCommand {
+			positional = values
+			break
+		}
+		if next < 0 {
+			// no subcommand; the command itself is invoked
+			positional = values
+			break
+		}
+		name := args[next]
+		sub := cmd.find(name)
+		if sub == nil {
+			return ErrUnknownCommand(name, suggest(name, cmd.commandNames()))
+		}
+		cmd = sub
+		args = args[next+1:]
+	}
+
+	for _, toks := range tokens {
+		if wantsHelp(toks) {
+			usage, err := cmd.Usage(opts...)
+			if err != nil {
+				return err
+			}
+			if _, err = io.WriteString(cfg.output, usage); err != nil {
+				return err
+			}
+			return ErrHelp
+		}
+	}
+	// a misspelled arg may hide the command, e.g. "tool --verbos", or cause other errors in any command of the path
+	for _, s := range schemas {
+		if err := checkUnknown(s, cfg, tokens[s]); err != nil {
+			return err
+		}
+	}
+	if cmd.run == nil {
+		return ErrMissingCommand
+	}
+	for i, current := range path {
+		values := make([]string, 0)
+		if i == len(path)-1 {
+			values = positional
+		}
+		dest := current.dest
+		if dest == nil {
+			// no destination; only unknown args may be present
+			dest = &struct{}{}
+		}
+		if err := parseTokens(dest, schemas[i], cfg, tokens[schemas[i]], values, newParseResult(schemas[i])); err != nil {
+			return err
+		}
+	}
+	return cmd.run()
+}
+
+// names of the registered subcommands
+func (c *Command) commandNames() []string {
+	result := make([]string, 0, len(c.commands))
+	for _, cmd := range c.commands {
+		result = append(result, cmd.name)
+	}
+	return result
+}
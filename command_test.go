@@ -0,0 +1,177 @@
+package argv
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type CmdGlobal struct {
+	Verbose int    `argv:"verbose|v,optional,counter"`
+	Config  string `argv:"config,optional"`
+}
+
+type CmdGenCert struct {
+	Domain string `argv:"#0"`
+	Days   uint32 `argv:"days,default=365"`
+}
+
+type CmdRevoke struct {
+	Serial string `argv:"serial"`
+}
+
+type cmdTree struct {
+	global  *CmdGlobal
+	gencert *CmdGenCert
+	revoke  *CmdRevoke
+	called  string
+	root    *Command
+}
+
+func newCmdTree() *cmdTree {
+	tree := &cmdTree{
+		global:  &CmdGlobal{},
+		gencert: &CmdGenCert{},
+		revoke:  &CmdRevoke{},
+	}
+	run := func(name string) RunFunc {
+		return func() error {
+			tree.called = name
+			return nil
+		}
+	}
+	tree.root = NewCommand("tool", tree.global, nil).AddCommand(
+		NewCommand("cert", nil, nil).AddCommand(
+			NewCommand("gencert", tree.gencert, run("gencert")),
+			NewCommand("revoke", tree.revoke, run("revoke")),
+		),
+		NewCommand("version", nil, run("version")),
+	)
+	return tree
+}
+
+func TestCommandExecute(t *testing.T) {
+	testCases := []struct {
+		name            string
+		args            []string
+		expected        string
+		expectedCalled  string
+		expectedGlobal  CmdGlobal
+		expectedGenCert CmdGenCert
+		expectedRevoke  CmdRevoke
+	}{
+		{
+			name:           "simple command",
+			args:           []string{"version"},
+			expectedCalled: "version",
+		},
+		{
+			name:            "nested command",
+			args:            []string{"cert", "gencert", "example.com", "-days", "30"},
+			expectedCalled:  "gencert",
+			expectedGenCert: CmdGenCert{Domain: "example.com", Days: 30},
+		},
+		{
+			name:            "global args before command",
+			args:            []string{"-vv", "--config", "x.conf", "cert", "gencert", "example.com"},
+			expectedCalled:  "gencert",
+			expectedGlobal:  CmdGlobal{Verbose: 2, Config: "x.conf"},
+			expectedGenCert: CmdGenCert{Domain: "example.com", Days: 365},
+		},
+		{
+			name:           "global args after command",
+			args:           []string{"-v", "cert", "-v", "revoke", "-serial", "01", "--config=x.conf", "-v"},
+			expectedCalled: "revoke",
+			expectedGlobal: CmdGlobal{Verbose: 3, Config: "x.conf"},
+			expectedRevoke: CmdRevoke{Serial: "01"},
+		},
+		{
+			name:     "missing command",
+			args:     []string{"-v"},
+			expected: "missing command",
+		},
+		{
+			name:     "missing subcommand",
+			args:     []string{"cert"},
+			expected: "missing command",
+		},
+		{
+			name:     "unknown command",
+			args:     []string{"cert", "gencrt", "example.com"},
+			expected: "unknown command 'gencrt'; did you mean 'gencert'?",
+		},
+		{
+			name:     "unknown arg",
+			args:     []string{"cert", "revoke", "-serial", "01", "-verbos"},
+			expected: "unknown arg 'verbos'; did you mean 'verbose'?",
+		},
+		{
+			// unknown args do not take the command name as value
+			name:     "unknown arg before command",
+			args:     []string{"--verbos", "cert", "revoke", "-serial", "01"},
+			expected: "unknown arg 'verbos'; did you mean 'verbose'?",
+		},
+		{
+			name:     "unknown arg and missing command",
+			args:     []string{"--verbos", "cert"},
+			expected: "unknown arg 'verbos'; did you mean 'verbose'?",
+		},
+		{
+			// typos are reported before the missing args they cause
+			name:     "unknown arg and missing positional",
+			args:     []string{"cert", "gencert", "--dasy", "5"},
+			expected: "unknown arg 'dasy'; did you mean 'days'?",
+		},
+		{
+			name:     "unknown global arg and missing command arg",
+			args:     []string{"--verbos", "cert", "revoke"},
+			expected: "unknown arg 'verbos'; did you mean 'verbose'?",
+		},
+		{
+			name:     "command args",
+			args:     []string{"cert", "revoke"},
+			expected: "value for arg 'serial' is missing",
+		},
+		{
+			name:     "args of sibling command",
+			args:     []string{"cert", "revoke", "-serial", "01", "-days", "3"},
+			expected: "unknown arg 'days'",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tree := newCmdTree()
+			err := tree.root.Execute(tc.args)
+			if len(tc.expected) > 0 {
+				assert.EqualError(t, err, tc.expected)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedCalled, tree.called)
+			assert.Equal(t, &tc.expectedGlobal, tree.global)
+			assert.Equal(t, &tc.expectedGenCert, tree.gencert)
+			assert.Equal(t, &tc.expectedRevoke, tree.revoke)
+		})
+	}
+}
+
+func TestCommandPath(t *testing.T) {
+	tree := newCmdTree()
+	cert := tree.root.Commands()[0]
+	assert.Equal(t, "cert", cert.Name())
+	assert.Equal(t, "tool cert gencert", cert.Commands()[0].Path())
+}
+
+func TestCommandDuplicate(t *testing.T) {
+	root := NewCommand("tool", nil, nil).AddCommand(NewCommand("version", nil, nil))
+	assert.PanicsWithValue(t, "argv: duplicate command 'version' in tool", func() {
+		root.AddCommand(NewCommand("version", nil, nil))
+	})
+}
+
+func TestCommandPositional(t *testing.T) {
+	root := NewCommand("tool", &CmdGenCert{}, nil).AddCommand(NewCommand("version", nil, func() error {
+		return nil
+	}))
+	assert.ErrorIs(t, root.Execute([]string{"version"}), ErrPositionalCommand)
+}
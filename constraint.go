@@ -0,0 +1,146 @@
+package argv
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ConstraintExclusive  = "exclusive"  // at most one arg of the group may be set
+	ConstraintTogether   = "together"   // args of the group are set together, or not at all
+	ConstraintRequiredIf = "requiredif" // arg is required if any of its condition args is set
+)
+
+// Constraint is a cross-field constraint, declared with the exclusive, together and requiredif tag options
+// Constraints are evaluated after parsing; args are set if supplied as argument or environment variable, so default
+// values do not count.
+type Constraint struct {
+	Kind       string       // ConstraintExclusive, ConstraintTogether or ConstraintRequiredIf
+	Group      string       // group name, as declared, for exclusive and together constraints
+	Args       []string     // args in the group, or the required arg, by primary name
+	If         []string     // args requiring the arg when set, by primary name, for requiredif constraints
+	specs      []*FieldSpec // fields of Args
+	conditions []*FieldSpec // fields of If
+}
+
+// Rule returns the constraint as declared, e.g. "exclusive=tls"
+func (c *Constraint) Rule() string {
+	if c.Kind == ConstraintRequiredIf {
+		return c.Kind + "=" + strings.Join(c.If, tagAlias)
+	}
+	return c.Kind + "=" + c.Group
+}
+
+// collect the constraints declared in a field tag
+func (s *Schema) addConstraints(tag tagSpec, spec *FieldSpec) error {
+	for _, kind := range []string{ConstraintExclusive, ConstraintTogether} {
+		group, ok := tag.get(kind)
+		if !ok {
+			continue
+		}
+		if len(group) == 0 {
+			return fmt.Errorf("empty %s group", kind)
+		}
+		c := s.constraint(kind, group)
+		c.Args = append(c.Args, spec.Name)
+		c.specs = append(c.specs, spec)
+	}
+	if value, ok := tag.get(ConstraintRequiredIf); ok {
+		// condition names are resolved once all fields are known
+		s.Constraints = append(s.Constraints, &Constraint{
+			Kind:  ConstraintRequiredIf,
+			Args:  []string{spec.Name},
+			If:    strings.Split(value, tagAlias),
+			specs: []*FieldSpec{spec},
+		})
+	}
+	return nil
+}
+
+// find a group constraint, adding it if not found
+func (s *Schema) constraint(kind string, group string) *Constraint {
+	for _, c := range s.Constraints {
+		if c.Kind == kind && c.Group == group {
+			return c
+		}
+	}
+	c := &Constraint{Kind: kind, Group: group}
+	s.Constraints = append(s.Constraints, c)
+	return c
+}
+
+// validate the declared constraints, and resolve requiredif conditions to their fields
+func (s *Schema) compileConstraints() error {
+	for _, c := range s.Constraints {
+		if c.Kind != ConstraintRequiredIf {
+			if len(c.specs) < 2 {
+				return ErrInvalidTag(c.specs[0].Path, fmt.Errorf("%s group '%s' requires at least two args", c.Kind, c.Group))
+			}
+			continue
+		}
+		for i, name := range c.If {
+			spec, ok := s.named[name]
+			switch {
+			case !ok:
+				return ErrInvalidTag(c.specs[0].Path, fmt.Errorf("unknown arg '%s' in requiredif", name))
+			case spec == c.specs[0]:
+				return ErrInvalidTag(c.specs[0].Path, fmt.Errorf("requiredif refers to the field itself"))
+			}
+			c.If[i] = spec.Name
+			c.conditions = append(c.conditions, spec)
+		}
+	}
+	return nil
+}
+
+// check the constraints against the assigned fields
+func (s *Schema) checkConstraints(result *ParseResult) error {
+	for _, c := range s.Constraints {
+		set := make([]string, 0, len(c.specs))
+		missing := make([]string, 0, len(c.specs))
+		for _, spec := range c.specs {
+			if result.isSet(spec) {
+				set = append(set, spec.Name)
+			} else {
+				missing = append(missing, spec.Name)
+			}
+		}
+		switch c.Kind {
+		case ConstraintExclusive:
+			if len(set) > 1 {
+				return ErrConstraint(c.Rule(), set, fmt.Errorf("args %s are mutually exclusive", strings.Join(set, ", ")))
+			}
+		case ConstraintTogether:
+			if len(set) > 0 && len(missing) > 0 {
+				return ErrConstraint(c.Rule(), c.Args, fmt.Errorf("args %s must be used together; missing %s",
+					strings.Join(c.Args, ", "), strings.Join(missing, ", ")))
+			}
+		case ConstraintRequiredIf:
+			if len(missing) == 0 {
+				continue
+			}
+			for _, cond := range c.conditions {
+				if result.isSet(cond) {
+					return ErrConstraint(c.Rule(), []string{c.Args[0], cond.Name}, fmt.Errorf("arg %s is required when %s is set",
+						c.Args[0], cond.Name))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// constraint description, for usage output, e.g. "mutually exclusive"
+func (c *Constraint) describe() string {
+	switch c.Kind {
+	case ConstraintExclusive:
+		return "mutually exclusive"
+	case ConstraintTogether:
+		return "required together"
+	}
+	labels := make([]string, len(c.conditions))
+	for i, spec := range c.conditions {
+		labels[i] = argLabel(spec)
+	}
+	return "required if " + strings.Join(labels, " or ") + " is set"
+}
@@ -0,0 +1,139 @@
+package argv
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type ConstraintStruct struct {
+	Cert       string `argv:"cert,optional,exclusive=source"`
+	SelfSigned bool   `argv:"self-signed|s,optional,exclusive=source"`
+	Key        string `argv:"key,optional,together=pair,env=KEY"`
+	Chain      string `argv:"chain,optional,together=pair"`
+	KeyPass    string `argv:"key-pass,optional,requiredif=key|p"`
+	Password   string `argv:"p,optional,default=secret"`
+	Days       int    `argv:"days,default=365,exclusive=validity"`
+	Until      string `argv:"until,optional,exclusive=validity"`
+}
+
+type ConstraintSingle struct {
+	Cert string `argv:"cert,optional,exclusive=source"`
+}
+
+type ConstraintUnknown struct {
+	KeyPass string `argv:"key-pass,optional,requiredif=key"`
+}
+
+type ConstraintSelf struct {
+	KeyPass string `argv:"key-pass|k,optional,requiredif=k"`
+}
+
+type ConstraintPositional struct {
+	Name string `argv:"#0,exclusive=source"`
+}
+
+func TestParseArgvConstraints(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		expected error
+	}{
+		{
+			name: "valid",
+			args: []string{"--cert", "a.pem", "--key", "a.key", "--chain", "ca.pem", "--key-pass", "x", "--until", "2030-01-01"},
+		},
+		{
+			// default values do not count as set
+			name: "defaults",
+			args: []string{"-s"},
+		},
+		{
+			name:     "exclusive",
+			args:     []string{"--cert", "a.pem", "-s"},
+			expected: ErrConstraint("exclusive=source", []string{"cert", "self-signed"}, fmt.Errorf("args cert, self-signed are mutually exclusive")),
+		},
+		{
+			name:     "exclusive with default",
+			args:     []string{"--days", "30", "--until", "2030-01-01"},
+			expected: ErrConstraint("exclusive=validity", []string{"days", "until"}, fmt.Errorf("args days, until are mutually exclusive")),
+		},
+		{
+			name:     "together",
+			args:     []string{"--chain", "ca.pem"},
+			expected: ErrConstraint("together=pair", []string{"key", "chain"}, fmt.Errorf("args key, chain must be used together; missing key")),
+		},
+		{
+			name:     "together from env",
+			args:     []string{"-s"},
+			env:      map[string]string{"KEY": "a.key"},
+			expected: ErrConstraint("together=pair", []string{"key", "chain"}, fmt.Errorf("args key, chain must be used together; missing chain")),
+		},
+		{
+			name:     "requiredif",
+			args:     []string{"--key", "a.key", "--chain", "ca.pem"},
+			expected: ErrConstraint("requiredif=key|p", []string{"key-pass", "key"}, fmt.Errorf("arg key-pass is required when key is set")),
+		},
+		{
+			name:     "requiredif any",
+			args:     []string{"-p", "other"},
+			expected: ErrConstraint("requiredif=key|p", []string{"key-pass", "p"}, fmt.Errorf("arg key-pass is required when p is set")),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ParseArgv(&ConstraintStruct{}, tc.args, WithEnvLookup(envMap(tc.env)))
+			if tc.expected == nil {
+				assert.Nil(t, err)
+				return
+			}
+			assert.Equal(t, tc.expected, err)
+		})
+	}
+
+	err := ParseArgv(&ConstraintStruct{}, []string{"--cert", "a.pem", "--self-signed"})
+	assert.EqualError(t, err, "args cert, self-signed are mutually exclusive")
+	assert.Equal(t, ErrTypeConstraint, err.(FieldError).ErrorType)
+}
+
+func TestConstraintsSchema(t *testing.T) {
+	s, err := ParseSchema(&ConstraintStruct{})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(s.Constraints))
+	assert.Equal(t, ConstraintExclusive, s.Constraints[0].Kind)
+	assert.Equal(t, "source", s.Constraints[0].Group)
+	assert.Equal(t, []string{"cert", "self-signed"}, s.Constraints[0].Args)
+	assert.Equal(t, []string{"key", "chain"}, s.Constraints[1].Args)
+	assert.Equal(t, ConstraintRequiredIf, s.Constraints[2].Kind)
+	assert.Equal(t, []string{"key-pass"}, s.Constraints[2].Args)
+	assert.Equal(t, []string{"key", "p"}, s.Constraints[2].If)
+	assert.Equal(t, "requiredif=key|p", s.Constraints[2].Rule())
+	assert.Equal(t, "exclusive=validity", s.Constraints[3].Rule())
+
+	usage, err := Usage("gencert", &ConstraintStruct{}, WithWidth(80))
+	assert.Nil(t, err)
+	expected := `
+Constraints:
+  --cert, --self-signed  mutually exclusive
+  --key, --chain         required together
+  --key-pass             required if --key or -p is set
+  --days, --until        mutually exclusive
+`
+	assert.True(t, strings.HasSuffix(usage, expected), usage)
+
+	testCases := []struct {
+		dest     any
+		expected string
+	}{
+		{&ConstraintSingle{}, "invalid argv tag on field ConstraintSingle.Cert: exclusive group 'source' requires at least two args"},
+		{&ConstraintUnknown{}, "invalid argv tag on field ConstraintUnknown.KeyPass: unknown arg 'key' in requiredif"},
+		{&ConstraintSelf{}, "invalid argv tag on field ConstraintSelf.KeyPass: requiredif refers to the field itself"},
+		{&ConstraintPositional{}, "invalid argv tag on field ConstraintPositional.Name: constraints are not supported on positional fields"},
+	}
+	for _, tc := range testCases {
+		_, err := ParseSchema(tc.dest)
+		assert.EqualError(t, err, tc.expected)
+	}
+}
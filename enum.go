@@ -0,0 +1,129 @@
+package argv
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// separator of the choices= tag option values, e.g. "choices=rsa|ecdsa|ed25519"
+const choiceSeparator = "|"
+
+// names and values of a registered enum type
+type enum struct {
+	names  []string // ordered by value, for ordered kinds, and then by name
+	values map[string]reflect.Value
+}
+
+func newEnum[T any](values map[string]T) *enum {
+	e := &enum{
+		names:  make([]string, 0, len(values)),
+		values: make(map[string]reflect.Value, len(values)),
+	}
+	for name, v := range values {
+		value := v
+		e.names = append(e.names, name)
+		e.values[name] = reflect.ValueOf(&value).Elem()
+	}
+	sort.Strings(e.names)
+	sort.SliceStable(e.names, func(i, j int) bool {
+		return lessValue(e.values[e.names[i]], e.values[e.names[j]])
+	})
+	return e
+}
+
+// setter for enum fields, assigning the value registered for a name
+func (e *enum) setter(ignoreCase bool) setter {
+	return func(field reflect.Value, fValue string) error {
+		name, ok := matchChoice(fValue, e.names, ignoreCase)
+		if !ok {
+			return &choiceError{value: fValue, choices: e.names}
+		}
+		field.Set(e.values[name])
+		return nil
+	}
+}
+
+// compare values of ordered kinds; other kinds are considered equal
+func lessValue(a reflect.Value, b reflect.Value) bool {
+	switch {
+	case a.CanInt():
+		return a.Int() < b.Int()
+	case a.CanUint():
+		return a.Uint() < b.Uint()
+	case a.CanFloat():
+		return a.Float() < b.Float()
+	case a.Kind() == reflect.String:
+		return a.String() < b.String()
+	}
+	return false
+}
+
+// choices of a field, declared with the choices= tag option, or the names of a registered enum type
+func (p *Parser) fieldChoices(tag tagSpec, t reflect.Type) ([]string, error) {
+	e := p.enums[baseType(t)]
+	value, ok := tag.get("choices")
+	if !ok {
+		if e != nil {
+			return e.names, nil
+		}
+		if tag.has("nocase") {
+			return nil, fmt.Errorf("nocase requires choices or an enum field")
+		}
+		return nil, nil
+	}
+	choices := strings.Split(value, choiceSeparator)
+	for i, choice := range choices {
+		choices[i] = strings.TrimSpace(choice)
+		if len(choices[i]) == 0 {
+			return nil, fmt.Errorf("empty choice in '%s'", value)
+		}
+		// choices of an enum field restrict its names
+		if e != nil {
+			if _, ok := e.values[choices[i]]; !ok {
+				return nil, &choiceError{value: choices[i], choices: e.names}
+			}
+		}
+	}
+	return choices, nil
+}
+
+// wrap a setter, accepting only the given choices; values are replaced by the matching choice, so case-insensitive
+// matches are assigned as declared
+func choiceSetter(set setter, choices []string, ignoreCase bool) setter {
+	return func(field reflect.Value, fValue string) error {
+		choice, ok := matchChoice(fValue, choices, ignoreCase)
+		if !ok {
+			return &choiceError{value: fValue, choices: choices}
+		}
+		return set(field, choice)
+	}
+}
+
+// find the choice matching value; exact matches take precedence over case-insensitive ones
+func matchChoice(value string, choices []string, ignoreCase bool) (string, bool) {
+	for _, choice := range choices {
+		if value == choice {
+			return choice, true
+		}
+	}
+	if ignoreCase {
+		for _, choice := range choices {
+			if strings.EqualFold(value, choice) {
+				return choice, true
+			}
+		}
+	}
+	return "", false
+}
+
+// value not matching any of the valid choices
+type choiceError struct {
+	value   string
+	choices []string
+}
+
+func (e *choiceError) Error() string {
+	return fmt.Sprintf("invalid choice '%s', expected one of %s", e.value, strings.Join(e.choices, ", "))
+}
@@ -0,0 +1,153 @@
+package argv
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type enumLevel int
+
+const (
+	enumLevelLow enumLevel = iota + 1
+	enumLevelMedium
+	enumLevelHigh
+)
+
+var enumLevels = map[string]enumLevel{
+	"low":    enumLevelLow,
+	"medium": enumLevelMedium,
+	"high":   enumLevelHigh,
+	"max":    enumLevelHigh,
+}
+
+type ChoiceStruct struct {
+	Alg    string            `argv:"alg,default=rsa,choices=rsa|ecdsa|ed25519,help=key algorithm"`
+	Mode   string            `argv:"mode,optional,choices=Fast|Safe,nocase"`
+	Port   int               `argv:"port,optional,choices=80|443"`
+	Tags   []string          `argv:"tag,optional,choices=a|b|c"`
+	Labels map[string]string `argv:"label,optional,choices=on|off"`
+	Level  enumLevel         `argv:"level,optional,nocase"`
+	Levels []enumLevel       `argv:"levels,optional"`
+	Limit  *enumLevel        `argv:"limit,optional,choices=low|high"`
+}
+
+type ChoiceInvalidEnum struct {
+	Level enumLevel `argv:"level,choices=low|extreme"`
+}
+
+type ChoiceInvalidNoCase struct {
+	Name string `argv:"name,nocase"`
+}
+
+type ChoiceEmpty struct {
+	Name string `argv:"name,choices=a||b"`
+}
+
+func TestParseArgvChoices(t *testing.T) {
+	p := NewParser(WithEnum(enumLevels))
+
+	dest := &ChoiceStruct{}
+	err := p.ParseArgv(dest, []string{"--alg", "ecdsa", "--mode", "SAFE", "--port", "443", "--tag", "a,c", "--label",
+		"x=on,y=off", "--level", "High", "--levels", "low,max", "--limit", "low"})
+	assert.Nil(t, err)
+	assert.Equal(t, "ecdsa", dest.Alg)
+	// case-insensitive matches are assigned as declared
+	assert.Equal(t, "Safe", dest.Mode)
+	assert.Equal(t, 443, dest.Port)
+	assert.Equal(t, []string{"a", "c"}, dest.Tags)
+	assert.Equal(t, map[string]string{"x": "on", "y": "off"}, dest.Labels)
+	assert.Equal(t, enumLevelHigh, dest.Level)
+	assert.Equal(t, []enumLevel{enumLevelLow, enumLevelHigh}, dest.Levels)
+	assert.Equal(t, enumLevelLow, *dest.Limit)
+
+	dest = &ChoiceStruct{}
+	assert.Nil(t, p.ParseArgv(dest, []string{"--port", "80"}))
+	assert.Equal(t, "rsa", dest.Alg)
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected error
+	}{
+		{
+			name:     "string",
+			args:     []string{"--alg", "dsa"},
+			expected: ErrInvalidChoice("alg", &choiceError{"dsa", []string{"rsa", "ecdsa", "ed25519"}}, []string{"rsa", "ecdsa", "ed25519"}),
+		},
+		{
+			// matching is case-sensitive by default
+			name:     "case",
+			args:     []string{"--alg", "RSA"},
+			expected: ErrInvalidChoice("alg", &choiceError{"RSA", []string{"rsa", "ecdsa", "ed25519"}}, []string{"rsa", "ecdsa", "ed25519"}),
+		},
+		{
+			name:     "int",
+			args:     []string{"--port", "8080"},
+			expected: ErrInvalidChoice("port", &choiceError{"8080", []string{"80", "443"}}, []string{"80", "443"}),
+		},
+		{
+			name:     "enum",
+			args:     []string{"--levels", "low,extreme"},
+			expected: ErrInvalidChoice("levels", &choiceError{"extreme", []string{"low", "medium", "high", "max"}}, []string{"low", "medium", "high", "max"}),
+		},
+		{
+			name:     "restricted enum",
+			args:     []string{"--limit", "medium"},
+			expected: ErrInvalidChoice("limit", &choiceError{"medium", []string{"low", "high"}}, []string{"low", "high"}),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, p.ParseArgv(&ChoiceStruct{}, tc.args))
+		})
+	}
+
+	err = p.ParseArgv(&ChoiceStruct{}, []string{"--label", "x=maybe"})
+	assert.EqualError(t, err, "error parsing arg label, key 'x': invalid choice 'maybe', expected one of on, off")
+	assert.Equal(t, ErrTypeInvalidChoice, err.(FieldError).ErrorType)
+	assert.Equal(t, []string{"on", "off"}, err.(FieldError).Choices)
+
+	err = p.ParseArgv(&ChoiceStruct{}, []string{"--level", "extreme"})
+	assert.EqualError(t, err, "error parsing arg level: invalid choice 'extreme', expected one of low, medium, high, max")
+
+	err = p.ParseArgv(&ChoiceInvalidEnum{}, []string{"--level", "low"})
+	assert.EqualError(t, err, "invalid argv tag on field ChoiceInvalidEnum.Level: invalid choice 'extreme', expected one of low, medium, high, max")
+	err = p.ParseArgv(&ChoiceInvalidNoCase{}, []string{"--name", "x"})
+	assert.EqualError(t, err, "invalid argv tag on field ChoiceInvalidNoCase.Name: nocase requires choices or an enum field")
+	err = p.ParseArgv(&ChoiceEmpty{}, []string{"--name", "x"})
+	assert.EqualError(t, err, "invalid argv tag on field ChoiceEmpty.Name: empty choice in 'a||b'")
+}
+
+func TestChoicesSchema(t *testing.T) {
+	p := NewParser(WithEnum(enumLevels))
+	s, err := p.ParseSchema(&ChoiceStruct{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"rsa", "ecdsa", "ed25519"}, s.Lookup("alg").Choices)
+	assert.False(t, s.Lookup("alg").IgnoreCase)
+	assert.True(t, s.Lookup("mode").IgnoreCase)
+	// enum names are ordered by value, and then by name
+	assert.Equal(t, []string{"low", "medium", "high", "max"}, s.Lookup("level").Choices)
+	assert.Equal(t, []string{"low", "high"}, s.Lookup("limit").Choices)
+
+	usage, err := p.Usage("keygen", &ChoiceStruct{}, WithWidth(100))
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(usage, "key algorithm (choices: rsa|ecdsa|ed25519, default: rsa)"), usage)
+	assert.True(t, strings.Contains(usage, "(choices: low|medium|high|max)"), usage)
+}
+
+type enumColor string
+
+func TestRegisterEnum(t *testing.T) {
+	// enums without registration are regular fields
+	var dest struct {
+		Color enumColor `argv:"color"`
+	}
+	assert.Nil(t, ParseArgv(&dest, []string{"--color", "purple"}))
+
+	RegisterEnum(map[string]enumColor{"red": "#f00", "green": "#0f0"})
+	assert.Nil(t, ParseArgv(&dest, []string{"--color", "red"}))
+	assert.Equal(t, enumColor("#f00"), dest.Color)
+	err := ParseArgv(&dest, []string{"--color", "purple"})
+	assert.EqualError(t, err, "error parsing arg color: invalid choice 'purple', expected one of green, red")
+}
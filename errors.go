@@ -3,6 +3,7 @@ package argv
 import (
 	"fmt"
 	"github.com/oddbit-project/blueprint/utils"
+	"strings"
 )
 
 const (
@@ -11,19 +12,38 @@ const (
 	ErrInvalidDest           = utils.Error("dest must be a ptr")
 	ErrInvalidDestType       = utils.Error("invalid argument type; dest must be a struct")
 	ErrInvalidParameterCount = utils.Error("invalid parameter count")
+	ErrInvalidArgName        = utils.Error("invalid argument name")
+	ErrMissingCommand        = utils.Error("missing command")
+	ErrHelp                  = utils.Error("help requested")
+	ErrPositionalCommand     = utils.Error("commands with subcommands cannot declare positional args")
 
 	// field error types
-	ErrTypeReadOnly     = 1
-	ErrTypeMissingValue = 2
-	ErrTypeInvalidValue = 3
-	ErrTypeNotSupported = 4
+	ErrTypeReadOnly          = 1
+	ErrTypeMissingValue      = 2
+	ErrTypeInvalidValue      = 3
+	ErrTypeNotSupported      = 4
+	ErrTypeUnexpectedArg     = 5
+	ErrTypeMissingPositional = 6
+	ErrTypeInvalidTag        = 7
+	ErrTypeUnknownArg        = 8
+	ErrTypeInvalidCluster    = 9
+	ErrTypeUnknownCommand    = 10
+	ErrTypeDuplicateKey      = 11
+	ErrTypeInvalidChoice     = 12
+	ErrTypeValidation        = 13
+	ErrTypeConstraint        = 14
 )
 
 // field validation errors
 type FieldError struct {
-	FieldName  string
-	ErrorType  int
-	FieldError error
+	FieldName   string
+	ErrorType   int
+	FieldError  error
+	Args        []string            // unknown argument names, the offending short name cluster, or constrained args
+	Suggestions map[string][]string // ranked suggestions for each unknown argument, if any
+	Key         string              // offending map key, if any
+	Choices     []string            // valid choices, for invalid choice errors
+	Rule        string              // failed validation rule or constraint, as declared, e.g. "min=2048"
 }
 
 func ErrReadOnly(fieldName string) FieldError {
@@ -50,6 +70,56 @@ func ErrInvalidValue(fieldName string, fieldError error) FieldError {
 	}
 }
 
+// invalid value for a map key; fieldError may refer to either the key or the value
+func ErrInvalidKeyValue(fieldName string, key string, fieldError error) FieldError {
+	return FieldError{
+		FieldName:  fieldName,
+		ErrorType:  ErrTypeInvalidValue,
+		FieldError: fieldError,
+		Key:        key,
+	}
+}
+
+// value not matching the field choices; choices lists the valid values
+func ErrInvalidChoice(fieldName string, fieldError error, choices []string) FieldError {
+	return FieldError{
+		FieldName:  fieldName,
+		ErrorType:  ErrTypeInvalidChoice,
+		FieldError: fieldError,
+		Choices:    choices,
+	}
+}
+
+// value failing a validation rule, e.g. "min=2048"
+func ErrValidation(fieldName string, rule string, fieldError error) FieldError {
+	return FieldError{
+		FieldName:  fieldName,
+		ErrorType:  ErrTypeValidation,
+		FieldError: fieldError,
+		Rule:       rule,
+	}
+}
+
+// violated cross-field constraint, e.g. "exclusive=tls"; args are the involved arg names
+func ErrConstraint(rule string, args []string, fieldError error) FieldError {
+	return FieldError{
+		FieldName:  args[0],
+		ErrorType:  ErrTypeConstraint,
+		FieldError: fieldError,
+		Args:       args,
+		Rule:       rule,
+	}
+}
+
+func ErrDuplicateKey(fieldName string, key string) FieldError {
+	return FieldError{
+		FieldName:  fieldName,
+		ErrorType:  ErrTypeDuplicateKey,
+		FieldError: nil,
+		Key:        key,
+	}
+}
+
 func ErrNotSupported(fieldName string) FieldError {
 	return FieldError{
 		FieldName:  fieldName,
@@ -58,6 +128,64 @@ func ErrNotSupported(fieldName string) FieldError {
 	}
 }
 
+func ErrUnexpectedArg(value string) FieldError {
+	return FieldError{
+		FieldName:  value,
+		ErrorType:  ErrTypeUnexpectedArg,
+		FieldError: nil,
+	}
+}
+
+func ErrMissingPositional(fieldName string) FieldError {
+	return FieldError{
+		FieldName:  fieldName,
+		ErrorType:  ErrTypeMissingPositional,
+		FieldError: nil,
+	}
+}
+
+// invalid tag; fieldName is the struct field name, as the tag itself may not be parseable
+func ErrInvalidTag(fieldName string, fieldError error) FieldError {
+	return FieldError{
+		FieldName:  fieldName,
+		ErrorType:  ErrTypeInvalidTag,
+		FieldError: fieldError,
+	}
+}
+
+func ErrUnknownArgs(args []string, suggestions map[string][]string) FieldError {
+	return FieldError{
+		FieldName:   args[0],
+		ErrorType:   ErrTypeUnknownArg,
+		FieldError:  nil,
+		Args:        args,
+		Suggestions: suggestions,
+	}
+}
+
+// unknown short name in a cluster, such as "-xqf"
+func ErrInvalidCluster(fieldName string, cluster string) FieldError {
+	return FieldError{
+		FieldName:  fieldName,
+		ErrorType:  ErrTypeInvalidCluster,
+		FieldError: nil,
+		Args:       []string{cluster},
+	}
+}
+
+func ErrUnknownCommand(name string, suggestions []string) FieldError {
+	result := FieldError{
+		FieldName:   name,
+		ErrorType:   ErrTypeUnknownCommand,
+		FieldError:  nil,
+		Suggestions: make(map[string][]string, 0),
+	}
+	if len(suggestions) > 0 {
+		result.Suggestions[name] = suggestions
+	}
+	return result
+}
+
 func (e FieldError) Error() string {
 	switch e.ErrorType {
 	case ErrTypeReadOnly:
@@ -66,7 +194,47 @@ func (e FieldError) Error() string {
 		return fmt.Sprintf("value for arg '%s' is missing", e.FieldName)
 	case ErrTypeNotSupported:
 		return fmt.Sprintf("non-supported type on arg %s", e.FieldName)
+	case ErrTypeUnexpectedArg:
+		return fmt.Sprintf("unexpected argument '%s'", e.FieldName)
+	case ErrTypeMissingPositional:
+		return fmt.Sprintf("value for positional arg %s is missing", e.FieldName)
+	case ErrTypeUnknownArg:
+		if len(e.Args) > 1 {
+			args := make([]string, len(e.Args))
+			for i, arg := range e.Args {
+				args[i] = fmt.Sprintf("'%s'", arg)
+				if s := e.Suggestions[arg]; len(s) > 0 {
+					args[i] += fmt.Sprintf(" (%s)", formatSuggestions(s))
+				}
+			}
+			return fmt.Sprintf("unknown args %s", strings.Join(args, ", "))
+		}
+		if s := e.Suggestions[e.FieldName]; len(s) > 0 {
+			return fmt.Sprintf("unknown arg '%s'; %s", e.FieldName, formatSuggestions(s))
+		}
+		return fmt.Sprintf("unknown arg '%s'", e.FieldName)
+	case ErrTypeInvalidCluster:
+		return fmt.Sprintf("unknown flag '-%s' in '%s'", e.FieldName, e.Args[0])
+	case ErrTypeUnknownCommand:
+		if s := e.Suggestions[e.FieldName]; len(s) > 0 {
+			return fmt.Sprintf("unknown command '%s'; %s", e.FieldName, formatSuggestions(s))
+		}
+		return fmt.Sprintf("unknown command '%s'", e.FieldName)
+	case ErrTypeDuplicateKey:
+		return fmt.Sprintf("duplicate key '%s' for arg %s", e.Key, e.FieldName)
+	case ErrTypeValidation:
+		if len(e.Key) > 0 {
+			return fmt.Sprintf("invalid value for arg %s, key '%s': %s", e.FieldName, e.Key, e.FieldError.Error())
+		}
+		return fmt.Sprintf("invalid value for arg %s: %s", e.FieldName, e.FieldError.Error())
+	case ErrTypeConstraint:
+		return e.FieldError.Error()
+	case ErrTypeInvalidTag:
+		return fmt.Sprintf("invalid argv tag on field %s: %s", e.FieldName, e.FieldError.Error())
 	default:
+		if len(e.Key) > 0 {
+			return fmt.Sprintf("error parsing arg %s, key '%s': %s", e.FieldName, e.Key, e.FieldError.Error())
+		}
 		return fmt.Sprintf("error parsing arg %s: %s", e.FieldName, e.FieldError.Error())
 	}
 }
@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"github.com/oddbit-project/argv"
 	"os"
-	"strings"
+	"path/filepath"
 )
 
+type GlobalOptions struct {
+	Verbose int `argv:"verbose|v,optional,counter,help=increase verbosity"`
+}
+
 type AlgorithmDetails struct {
 	Algorithm string `argv:"alg"`
 	KeyLen    uint   `argv:"bits"`
@@ -23,40 +27,41 @@ type CertInfo struct {
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Printf("Usage: %s <command> [options]\n", os.Args[0])
+	// destination structs
+	global := &GlobalOptions{}
+	record := &CertInfo{}
+
+	root := argv.NewCommand(filepath.Base(os.Args[0]), global, nil).AddCommand(
+		argv.NewCommand("cert", nil, nil).AddCommand(
+			argv.NewCommand("gencert", record, func() error {
+				str, _ := json.Marshal(record)
+				if global.Verbose > 0 {
+					fmt.Printf("Verbosity level: %d\n", global.Verbose)
+				}
+				fmt.Printf("Parsed parameters:\n %s\n", string(str))
+				return nil
+			}),
+		),
+	)
+
+	// attempt to parse & serialize values from command line
+	err := root.Execute(os.Args[1:])
+	if err == nil {
+		os.Exit(0)
+	}
+
+	// help was requested, and usage was already printed
+	if errors.Is(err, argv.ErrHelp) {
 		os.Exit(0)
 	}
-	cmd := os.Args[1]
-
-	switch cmd {
-	case "gencert":
-		// destination struct
-		record := &CertInfo{}
-
-		// attempt to parse & serialize values from command line
-		err := argv.ParseArgv(record, os.Args[2:])
-		if err == nil {
-			str, _ := json.Marshal(record)
-			fmt.Printf("Parsed parameters:\n %s\n", string(str))
-			os.Exit(0)
-		}
-
-		// empty arg list, show available names
-		if errors.Is(err, argv.ErrEmptyArgs) {
-			names, err := argv.ParseNames(record)
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(-1)
-			}
-			fmt.Println("Available args: ", strings.Join(names, ", "))
-			os.Exit(0)
-		}
-
-		fmt.Println(err)
-		os.Exit(-1)
-	default:
-		fmt.Printf("'%s' is not a supported command\n", cmd)
-		os.Exit(-1)
+
+	// no command, show usage
+	if errors.Is(err, argv.ErrMissingCommand) {
+		usage, _ := root.Usage()
+		fmt.Print(usage)
+		os.Exit(0)
 	}
+
+	fmt.Println(err)
+	os.Exit(-1)
 }
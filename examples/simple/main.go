@@ -6,53 +6,48 @@ import (
 	"fmt"
 	"github.com/oddbit-project/argv"
 	"os"
-	"strings"
+	"path/filepath"
 )
 
 type CertInfo struct {
-	CommonName         string `argv:"CN"`
-	OrganizationalUnit string `argv:"OU"`
-	Organization       string `argv:"O,optional"`
-	Algorithm          string `argv:"alg"`
-	KeyLen             uint   `argv:"bits"`
-	Days               uint32 `argv:"days"`
+	CommonName         string `argv:"CN,help=certificate common name"`
+	OrganizationalUnit string `argv:"OU,help=organizational unit"`
+	Organization       string `argv:"O,optional,help=organization"`
+	Algorithm          string `argv:"alg,help=key algorithm"`
+	KeyLen             uint   `argv:"bits,help='key length, in bits'"`
+	Days               uint32 `argv:"days,default=365,help='validity period, in days'"`
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Printf("Usage: %s <command> [options]\n", os.Args[0])
+	// destination struct
+	record := &CertInfo{}
+
+	root := argv.NewCommand(filepath.Base(os.Args[0]), nil, nil).AddCommand(
+		argv.NewCommand("gencert", record, func() error {
+			str, _ := json.Marshal(record)
+			fmt.Printf("Parsed parameters:\n %s\n", string(str))
+			return nil
+		}).WithDescription("generate a certificate"),
+	)
+
+	// attempt to parse & serialize values from command line
+	err := root.Execute(os.Args[1:])
+	if err == nil {
 		os.Exit(0)
 	}
-	cmd := os.Args[1]
 
-	switch cmd {
-	case "gencert":
-		// destination struct
-		record := &CertInfo{}
+	// help was requested, and usage was already printed
+	if errors.Is(err, argv.ErrHelp) {
+		os.Exit(0)
+	}
 
-		// attempt to parse & serialize values from command line
-		err := argv.ParseArgv(record, os.Args[2:])
-		if err == nil {
-			str, _ := json.Marshal(record)
-			fmt.Printf("Parsed parameters:\n %s\n", string(str))
-			os.Exit(0)
-		}
-
-		// empty arg list, show available names
-		if errors.Is(err, argv.ErrEmptyArgs) {
-			names, err := argv.ParseNames(record)
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(-1)
-			}
-			fmt.Println("Available args: ", strings.Join(names, ", "))
-			os.Exit(0)
-		}
-
-		fmt.Println(err)
-		os.Exit(-1)
-	default:
-		fmt.Printf("'%s' is not a supported command\n", cmd)
-		os.Exit(-1)
+	// no command, show usage
+	if errors.Is(err, argv.ErrMissingCommand) {
+		usage, _ := root.Usage()
+		fmt.Print(usage)
+		os.Exit(0)
 	}
+
+	fmt.Println(err)
+	os.Exit(-1)
 }
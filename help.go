@@ -0,0 +1,285 @@
+package argv
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultWidth        = 80
+	minDescriptionWidth = 24
+	maxLabelWidth       = 32
+	helpIndent          = "  "
+	helpShortName       = "h"
+	helpLongName        = "help"
+)
+
+// Usage renders the help text for dest, a pointer to a tagged struct; name is the program or command name
+// The output is wrapped to the terminal width, read from the COLUMNS environment variable; see WithWidth.
+func Usage(name string, dest any, opts ...Option) (string, error) {
+	return defaultParser.Usage(name, dest, opts...)
+}
+
+// Usage renders the help text for the command, including global args from parent commands
+func (c *Command) Usage(opts ...Option) (string, error) {
+	p := c.parserOf()
+	var parent *Schema
+	chain := make([]*Command, 0)
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		chain = append([]*Command{cmd}, chain...)
+	}
+	var s *Schema
+	for _, cmd := range chain {
+		var err error
+		if s, err = cmd.schema(p, parent); err != nil {
+			return "", err
+		}
+		parent = s
+	}
+	return renderUsage(c.Path(), c.description, s, c.commands, p.config(opts)), nil
+}
+
+// check if help was requested, and the destination does not declare the help names itself
+func wantsHelp(tokens []token) bool {
+	for _, tok := range tokens {
+		if tok.spec == nil && (tok.name == helpShortName || tok.name == helpLongName) {
+			return true
+		}
+	}
+	return false
+}
+
+// a single entry of a help section
+type helpEntry struct {
+	label       string
+	description string
+}
+
+// a titled help section
+type helpSection struct {
+	title   string
+	entries []helpEntry
+}
+
+func renderUsage(name string, description string, s *Schema, commands []*Command, cfg *config) string {
+	width := cfg.terminalWidth()
+	sections := make([]helpSection, 0)
+
+	// synopsis
+	synopsis := []string{"Usage:", name}
+	if len(s.named) > 0 || s.parent != nil {
+		synopsis = append(synopsis, "[options]")
+	}
+	if len(commands) > 0 {
+		synopsis = append(synopsis, "<command>")
+	}
+
+	// positional args
+	args := helpSection{title: "Arguments:"}
+	for _, spec := range s.Positional {
+		label := positionalLabel(spec)
+		if spec.Optional {
+			synopsis = append(synopsis, "["+label+"]")
+		} else {
+			synopsis = append(synopsis, label)
+		}
+		args.entries = append(args.entries, helpEntry{label: label, description: describe(spec)})
+	}
+	if s.Rest != nil {
+		label := positionalLabel(s.Rest) + "..."
+		if s.Rest.Optional {
+			synopsis = append(synopsis, "["+label+"]")
+		} else {
+			synopsis = append(synopsis, label)
+		}
+		args.entries = append(args.entries, helpEntry{label: label, description: describe(s.Rest)})
+	}
+	sections = append(sections, args)
+
+	// subcommands
+	cmds := helpSection{title: "Commands:"}
+	for _, cmd := range commands {
+		cmds.entries = append(cmds.entries, helpEntry{label: cmd.name, description: cmd.description})
+	}
+	sections = append(sections, cmds)
+
+	// named args, grouped by nested struct
+	sections = append(sections, optionSections(s, "Options:")...)
+	for parent := s.parent; parent != nil; parent = parent.parent {
+		sections = append(sections, optionSections(parent, "Global options:")...)
+	}
+
+	// cross-field constraints
+	constraints := helpSection{title: "Constraints:"}
+	for _, c := range s.Constraints {
+		labels := make([]string, len(c.specs))
+		for i, spec := range c.specs {
+			labels[i] = argLabel(spec)
+		}
+		constraints.entries = append(constraints.entries, helpEntry{label: strings.Join(labels, ", "), description: c.describe()})
+	}
+	sections = append(sections, constraints)
+
+	var sb strings.Builder
+	sb.WriteString(strings.Join(synopsis, " "))
+	sb.WriteString("\n")
+	if len(description) > 0 {
+		sb.WriteString("\n")
+		for _, line := range wrapText(description, width) {
+			sb.WriteString(line + "\n")
+		}
+	}
+	for _, section := range sections {
+		if len(section.entries) == 0 {
+			continue
+		}
+		sb.WriteString("\n" + section.title + "\n")
+		renderSection(&sb, section, width)
+	}
+	return sb.String()
+}
+
+// build option sections for named args, one per nested struct group
+func optionSections(s *Schema, title string) []helpSection {
+	result := make([]helpSection, 0)
+	for _, group := range s.Groups() {
+		section := helpSection{title: title}
+		if len(group) > 0 {
+			section.title = group + " options:"
+		}
+		for _, spec := range s.Named() {
+			if spec.Group == group {
+				section.entries = append(section.entries, helpEntry{label: optionLabel(spec), description: describe(spec)})
+			}
+		}
+		result = append(result, section)
+	}
+	return result
+}
+
+// render section entries in two columns, wrapping descriptions
+func renderSection(sb *strings.Builder, section helpSection, width int) {
+	labelWidth := 0
+	for _, entry := range section.entries {
+		if l := len(entry.label); l > labelWidth && l <= maxLabelWidth {
+			labelWidth = l
+		}
+	}
+	column := len(helpIndent) + labelWidth + len(helpIndent)
+	descWidth := width - column
+	if descWidth < minDescriptionWidth {
+		descWidth = minDescriptionWidth
+	}
+	padding := strings.Repeat(" ", column)
+	for _, entry := range section.entries {
+		lines := wrapText(entry.description, descWidth)
+		label := helpIndent + entry.label
+		if len(entry.label) > labelWidth && len(lines) > 0 {
+			// label too long, description starts on the next line
+			sb.WriteString(label + "\n")
+			label = ""
+		}
+		if len(lines) == 0 {
+			sb.WriteString(label + "\n")
+			continue
+		}
+		for i, line := range lines {
+			if i == 0 && len(label) > 0 {
+				sb.WriteString(label + strings.Repeat(" ", column-len(label)) + line + "\n")
+				continue
+			}
+			sb.WriteString(padding + line + "\n")
+		}
+	}
+}
+
+// positional arg label, derived from the field name, e.g. <domain>
+func positionalLabel(spec *FieldSpec) string {
+	return "<" + strings.ToLower(spec.Field) + ">"
+}
+
+// option label, e.g. "-d, --days uint32"
+func optionLabel(spec *FieldSpec) string {
+	short := make([]string, 0)
+	long := make([]string, 0)
+	for _, name := range spec.Names {
+		if isShortName(name) {
+			short = append(short, "-"+name)
+		} else {
+			long = append(long, "--"+name)
+		}
+	}
+	label := strings.Join(append(short, long...), ", ")
+	if len(short) == 0 {
+		// align long-only names with short+long entries
+		label = "    " + label
+	}
+	if !spec.IsFlag() {
+		label += " " + typeLabel(spec.Type)
+	}
+	return label
+}
+
+// primary arg name, with dashes, e.g. --days
+func argLabel(spec *FieldSpec) string {
+	if isShortName(spec.Name) {
+		return "-" + spec.Name
+	}
+	return "--" + spec.Name
+}
+
+// type name, for display purposes; pointers are shown as their element type
+func typeLabel(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.String()
+}
+
+// description with optional details, e.g. "validity (default: 365, env: CERT_DAYS)"
+func describe(spec *FieldSpec) string {
+	details := make([]string, 0)
+	if !spec.Optional {
+		details = append(details, "required")
+	}
+	if len(spec.Choices) > 0 {
+		details = append(details, "choices: "+strings.Join(spec.Choices, choiceSeparator))
+	}
+	if spec.HasDefault {
+		value := spec.Default
+		if len(value) == 0 || strings.ContainsAny(value, " \t,") {
+			value = strconv.Quote(value)
+		}
+		details = append(details, "default: "+value)
+	}
+	if len(spec.Env) > 0 {
+		details = append(details, "env: "+spec.Env)
+	}
+	if len(details) == 0 {
+		return spec.Help
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s (%s)", spec.Help, strings.Join(details, ", ")))
+}
+
+// wrapText splits text into lines of at most width characters, breaking on whitespace
+func wrapText(text string, width int) []string {
+	result := make([]string, 0)
+	line := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case len(line) == 0:
+			line = word
+		case len(line)+1+len(word) > width:
+			result = append(result, line)
+			line = word
+		default:
+			line += " " + word
+		}
+	}
+	if len(line) > 0 {
+		result = append(result, line)
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+package argv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type ArgStructHelp struct {
+	Domain  string `argv:"#0,help=domain name"`
+	Output  string `argv:"#1,optional,help=output file"`
+	Days    uint32 `argv:"days|d,default=365,env=CERT_DAYS,help='certificate validity period, in days'"`
+	Verbose bool   `argv:"verbose|v,optional,help=verbose output"`
+	Alg     struct {
+		Name string `argv:"alg,help=key algorithm"`
+		Bits uint   `argv:"bits,default=2048"`
+	}
+}
+
+func TestUsage(t *testing.T) {
+	expected := `Usage: gencert [options] <domain> [<output>]
+
+Arguments:
+  <domain>  domain name (required)
+  <output>  output file
+
+Options:
+  -d, --days uint32  certificate validity period, in days (default:
+                     365, env: CERT_DAYS)
+  -v, --verbose      verbose output
+
+Alg options:
+      --alg string  key algorithm (required)
+      --bits uint   (default: 2048)
+`
+	usage, err := Usage("gencert", &ArgStructHelp{}, WithWidth(70))
+	assert.Nil(t, err)
+	assert.Equal(t, expected, usage)
+
+	// width from environment
+	usage, err = Usage("gencert", &ArgStructHelp{}, WithEnvLookup(envMap(map[string]string{"COLUMNS": "70"})))
+	assert.Nil(t, err)
+	assert.Equal(t, expected, usage)
+}
+
+func TestCommandUsage(t *testing.T) {
+	tree := newCmdTree()
+	tree.root.WithDescription("certificate management tool")
+	expected := `Usage: tool [options] <command>
+
+certificate management tool
+
+Commands:
+  cert
+  version
+
+Options:
+  -v, --verbose
+      --config string
+`
+	usage, err := tree.root.Usage()
+	assert.Nil(t, err)
+	assert.Equal(t, expected, usage)
+
+	expected = `Usage: tool cert gencert [options] <domain>
+
+Arguments:
+  <domain>  (required)
+
+Options:
+      --days uint32  (default: 365)
+
+Global options:
+  -v, --verbose
+      --config string
+`
+	var out bytes.Buffer
+	err = tree.root.Execute([]string{"cert", "gencert", "--help"}, WithOutput(&out))
+	assert.ErrorIs(t, err, ErrHelp)
+	assert.Equal(t, expected, out.String())
+	assert.Equal(t, "", tree.called)
+}
+
+func TestParseArgvHelp(t *testing.T) {
+	// help takes precedence over missing values
+	assert.ErrorIs(t, ParseArgv(&ArgStructHelp{}, []string{"-h"}), ErrHelp)
+	assert.ErrorIs(t, ParseArgv(&ArgStructHelp{}, []string{"example.com", "--help"}), ErrHelp)
+
+	// help names declared by the destination are regular args
+	dest := &ArgStructFlags{}
+	assert.Nil(t, ParseArgv(&struct {
+		Help bool `argv:"help|h,optional"`
+	}{}, []string{"-h"}))
+	assert.EqualError(t, ParseArgv(dest, []string{"-name", "x", "-helps"}), "unknown arg 'helps'")
+}
+
+func TestWrapText(t *testing.T) {
+	assert.Equal(t, []string{}, wrapText("", 10))
+	assert.Equal(t, []string{"a b", "c"}, wrapText("a b c", 3))
+	assert.Equal(t, []string{"verylongword", "a"}, wrapText("verylongword a", 5))
+}
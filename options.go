@@ -0,0 +1,158 @@
+package argv
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Option configures parsing behaviour
+type Option func(*config)
+
+// parsing configuration
+type config struct {
+	strict      bool
+	envPrefix   string
+	envLookup   func(name string) (string, bool)
+	width       int
+	output      io.Writer
+	tagName     string
+	parsers     map[string]FieldParser
+	typeParsers map[reflect.Type]setter
+	enums       map[reflect.Type]*enum
+	reserved    []string
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		strict:    true,
+		envLookup: os.LookupEnv,
+		output:    os.Stdout,
+		tagName:   annotationTag,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithStrict enables or disables rejection of unknown arguments; strict mode is enabled by default
+// In permissive mode, unknown arguments are ignored. In both modes, unknown arguments are collected into the
+// destination #extra field, if one is declared.
+func WithStrict(strict bool) Option {
+	return func(c *config) {
+		c.strict = strict
+	}
+}
+
+// WithEnvPrefix enables environment variable fallback for all named args, using the prefixed, upper-case arg
+// name as variable name; e.g. with prefix "CERT_", arg "key-len" is read from CERT_KEY_LEN
+// Fields with an explicit env= tag option use the declared variable name instead.
+func WithEnvPrefix(prefix string) Option {
+	return func(c *config) {
+		c.envPrefix = prefix
+	}
+}
+
+// WithEnvLookup sets the function used to read environment variables; defaults to os.LookupEnv
+func WithEnvLookup(fn func(name string) (string, bool)) Option {
+	return func(c *config) {
+		c.envLookup = fn
+	}
+}
+
+// envName returns the environment variable name for a field, if any
+func (c *config) envName(spec *FieldSpec) string {
+	if len(spec.Env) > 0 {
+		return spec.Env
+	}
+	if len(c.envPrefix) == 0 {
+		return ""
+	}
+	return c.envPrefix + strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(spec.Name))
+}
+
+// WithWidth sets the line width for usage output; defaults to the COLUMNS environment variable, or 80
+func WithWidth(width int) Option {
+	return func(c *config) {
+		c.width = width
+	}
+}
+
+// WithOutput sets the writer used by Command.Execute to print usage on -h/--help; defaults to os.Stdout
+func WithOutput(w io.Writer) Option {
+	return func(c *config) {
+		c.output = w
+	}
+}
+
+// terminalWidth returns the configured line width, or the terminal width if available
+func (c *config) terminalWidth() int {
+	if c.width > 0 {
+		return c.width
+	}
+	if columns, ok := c.envLookup("COLUMNS"); ok {
+		if width, err := strconv.Atoi(columns); err == nil && width > 0 {
+			return width
+		}
+	}
+	return defaultWidth
+}
+
+// WithTagName sets the struct tag key read by the parser; defaults to "argv"
+// Only applies to NewParser.
+func WithTagName(name string) Option {
+	return func(c *config) {
+		c.tagName = name
+	}
+}
+
+// WithFieldParser adds a custom field parser, by type name, e.g. "net.IP"; see Parser.AddParser
+// Only applies to NewParser.
+//
+// Deprecated: type names are ambiguous; use WithTypeParser instead.
+func WithFieldParser(typeName string, fn FieldParser) Option {
+	return func(c *config) {
+		if c.parsers == nil {
+			c.parsers = make(map[string]FieldParser, 0)
+		}
+		c.parsers[typeName] = fn
+	}
+}
+
+// WithTypeParser adds a custom field parser, for fields of type T; takes precedence over built-in conversions
+// Only applies to NewParser.
+func WithTypeParser[T any](fn func(in string) (T, error)) Option {
+	return func(c *config) {
+		if c.typeParsers == nil {
+			c.typeParsers = make(map[reflect.Type]setter, 0)
+		}
+		c.typeParsers[typeOf[T]()] = typedSetter(fn)
+	}
+}
+
+// WithEnum registers the names of the values of type T, such as named constants; see RegisterEnum
+// Only applies to NewParser.
+func WithEnum[T any](values map[string]T) Option {
+	return func(c *config) {
+		if c.enums == nil {
+			c.enums = make(map[reflect.Type]*enum, 0)
+		}
+		c.enums[typeOf[T]()] = newEnum(values)
+	}
+}
+
+// WithReservedType adds a struct type that should not be recursively parsed, by type name
+// Only applies to NewParser.
+func WithReservedType(typeName string) Option {
+	return func(c *config) {
+		c.reserved = append(c.reserved, typeName)
+	}
+}
@@ -0,0 +1,105 @@
+package argv
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type ArgStructEnv struct {
+	CommonName string `argv:"CN"`
+	Days       uint32 `argv:"days,env=CERT_DAYS,default=365"`
+	KeyLen     uint   `argv:"key-len,optional"`
+}
+
+type ArgStructEnvPositional struct {
+	Domain string `argv:"#0,env=DOMAIN"`
+}
+
+func envMap(values map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := values[name]
+		return v, ok
+	}
+}
+
+func TestParseArgvEnv(t *testing.T) {
+	testCases := []struct {
+		name           string
+		args           []string
+		env            map[string]string
+		opts           []Option
+		expected       string
+		expectedValues ArgStructEnv
+	}{
+		{
+			name:           "default value",
+			args:           []string{"-CN", "x"},
+			env:            map[string]string{},
+			expectedValues: ArgStructEnv{CommonName: "x", Days: 365},
+		},
+		{
+			name:           "env value",
+			args:           []string{"-CN", "x"},
+			env:            map[string]string{"CERT_DAYS": "30"},
+			expectedValues: ArgStructEnv{CommonName: "x", Days: 30},
+		},
+		{
+			name:           "argv takes precedence",
+			args:           []string{"-CN", "x", "-days", "10"},
+			env:            map[string]string{"CERT_DAYS": "30"},
+			expectedValues: ArgStructEnv{CommonName: "x", Days: 10},
+		},
+		{
+			name:     "invalid env value",
+			args:     []string{"-CN", "x"},
+			env:      map[string]string{"CERT_DAYS": "never"},
+			expected: "error parsing arg days: environment variable CERT_DAYS: strconv.ParseUint: parsing \"never\": invalid syntax",
+		},
+		{
+			name:     "prefix not enabled",
+			args:     []string{"--"},
+			env:      map[string]string{"APP_CN": "y"},
+			expected: "value for arg 'CN' is missing",
+		},
+		{
+			name:           "prefix",
+			args:           []string{"--"},
+			env:            map[string]string{"APP_CN": "y", "APP_KEY_LEN": "2048", "APP_DAYS": "1", "CERT_DAYS": "2"},
+			opts:           []Option{WithEnvPrefix("APP_")},
+			expectedValues: ArgStructEnv{CommonName: "y", Days: 2, KeyLen: 2048},
+		},
+		{
+			// e.g. a container launched without arguments
+			name:           "no args, env and defaults",
+			args:           []string{},
+			env:            map[string]string{"APP_CN": "y"},
+			opts:           []Option{WithEnvPrefix("APP_")},
+			expectedValues: ArgStructEnv{CommonName: "y", Days: 365},
+		},
+		{
+			name:     "no args, missing required",
+			args:     []string{},
+			env:      map[string]string{"CERT_DAYS": "30"},
+			expected: "empty argument list",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dest := &ArgStructEnv{}
+			opts := append([]Option{WithEnvLookup(envMap(tc.env))}, tc.opts...)
+			err := ParseArgv(dest, tc.args, opts...)
+			if len(tc.expected) > 0 {
+				assert.EqualError(t, err, tc.expected)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, &tc.expectedValues, dest)
+		})
+	}
+}
+
+func TestParseArgvEnvPositional(t *testing.T) {
+	err := ParseArgv(&ArgStructEnvPositional{}, []string{"x"})
+	assert.EqualError(t, err, "invalid argv tag on field ArgStructEnvPositional.Domain: env is not supported on positional fields")
+}
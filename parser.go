@@ -0,0 +1,233 @@
+package argv
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Parser parses arguments into tagged structs
+//
+// Each parser owns its custom field parsers, reserved types, tag name and compiled schema cache, so independent
+// libraries in the same program do not interfere with each other. Parsers are safe for concurrent use. The
+// top-level functions, such as ParseArgv, use a default parser.
+type Parser struct {
+	opts        []Option // default options, applied before per-call options
+	tagName     string
+	mu          sync.RWMutex
+	parsers     map[string]FieldParser
+	typeParsers map[reflect.Type]setter
+	enums       map[reflect.Type]*enum
+	reserved    map[string]bool
+	cache       sync.Map // compiled schemas, by destination struct type
+}
+
+// parser used by the top-level functions
+var defaultParser = NewParser()
+
+// NewParser creates a new parser; opts are used as defaults for all calls, and may be overridden per call
+// Options configuring the parser itself, such as WithTagName or WithTypeParser, are ignored when used per call.
+func NewParser(opts ...Option) *Parser {
+	cfg := newConfig(opts)
+	p := &Parser{
+		opts:        opts,
+		tagName:     cfg.tagName,
+		parsers:     make(map[string]FieldParser, len(cfg.parsers)),
+		typeParsers: make(map[reflect.Type]setter, len(cfg.typeParsers)),
+		enums:       make(map[reflect.Type]*enum, len(cfg.enums)),
+		reserved:    map[string]bool{"time.Time": true, "time.Location": true},
+	}
+	for name, fn := range cfg.parsers {
+		p.parsers[name] = fn
+	}
+	for t, set := range cfg.typeParsers {
+		p.typeParsers[t] = set
+	}
+	for t, e := range cfg.enums {
+		p.enums[t] = e
+	}
+	for _, name := range cfg.reserved {
+		p.reserved[name] = true
+	}
+	return p
+}
+
+// AddParser adds a custom field parser, by type name, e.g. "net.IP"
+// Parsers are resolved when a struct is first parsed; they should be registered on program initialization.
+//
+// Deprecated: type names are ambiguous; use WithTypeParser instead.
+func (p *Parser) AddParser(in string, fn FieldParser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.parsers[in] = fn
+	p.resetCache()
+}
+
+// add a custom field parser, by field type
+func (p *Parser) addTypeParser(t reflect.Type, set setter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.typeParsers[t] = set
+	p.resetCache()
+}
+
+// add an enum type, by field type
+func (p *Parser) addEnum(t reflect.Type, e *enum) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.enums[t] = e
+	p.resetCache()
+}
+
+// AddReservedType adds a struct type that should not be recursively parsed, by type name
+func (p *Parser) AddReservedType(t string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reserved[t] = true
+	p.resetCache()
+}
+
+// ParseArgv parses argv into dest, a pointer to a tagged struct; see ParseArgv
+func (p *Parser) ParseArgv(dest any, argv []string, opts ...Option) error {
+	_, err := p.Parse(dest, argv, opts...)
+	return err
+}
+
+// Parse parses argv into dest, and reports which fields were assigned; see Parse
+func (p *Parser) Parse(dest any, argv []string, opts ...Option) (*ParseResult, error) {
+	cfg := p.config(opts)
+	s, err := p.schema(dest)
+	if err != nil {
+		return nil, err
+	}
+	tokens, positional, err := extractArgs(argv, s)
+	if err != nil {
+		return nil, err
+	}
+	if wantsHelp(tokens) {
+		return nil, ErrHelp
+	}
+	result := newParseResult(s)
+	if err := parseTokens(dest, s, cfg, tokens, positional, result); err != nil {
+		// without arguments, env and defaults may still fill every required field
+		if len(argv) == 0 && isMissing(err) {
+			return nil, ErrEmptyArgs
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// check if err reports a missing required arg
+func isMissing(err error) bool {
+	fieldErr, ok := err.(FieldError)
+	return ok && (fieldErr.ErrorType == ErrTypeMissingValue || fieldErr.ErrorType == ErrTypeMissingPositional)
+}
+
+// ParseSchema returns the argument schema of dest, a pointer to a tagged struct
+func (p *Parser) ParseSchema(dest any) (*Schema, error) {
+	return p.schema(dest)
+}
+
+// ParseNames returns the named args declared in dest; see ParseNames
+func (p *Parser) ParseNames(dest any) ([]string, error) {
+	s, err := p.schema(dest)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(s.Fields))
+	for _, spec := range s.Named() {
+		result = append(result, strings.Join(spec.Names, tagAlias))
+	}
+	return result, nil
+}
+
+// ParseDefaults returns the default values declared in dest tags, by arg name
+func (p *Parser) ParseDefaults(dest any) (map[string]string, error) {
+	s, err := p.schema(dest)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, 0)
+	for _, spec := range s.Fields {
+		if spec.HasDefault {
+			result[spec.Name] = spec.Default
+		}
+	}
+	return result, nil
+}
+
+// Usage renders the help text for dest; see Usage
+func (p *Parser) Usage(name string, dest any, opts ...Option) (string, error) {
+	s, err := p.schema(dest)
+	if err != nil {
+		return "", err
+	}
+	return renderUsage(name, "", s, nil, p.config(opts)), nil
+}
+
+// per-call configuration, with the parser defaults
+func (p *Parser) config(opts []Option) *config {
+	if len(p.opts) == 0 {
+		return newConfig(opts)
+	}
+	return newConfig(append(append(make([]Option, 0, len(p.opts)+len(opts)), p.opts...), opts...))
+}
+
+// schema returns the argument specification of the destination struct, compiling it on first use
+func (p *Parser) schema(dest any) (*Schema, error) {
+	t := reflect.TypeOf(dest)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return nil, ErrInvalidDest
+	}
+	t = t.Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, ErrInvalidDestType
+	}
+	// registries may not change while compiling, or a stale schema could be cached
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if s, ok := p.cache.Load(t); ok {
+		return s.(*Schema), nil
+	}
+	s, err := p.compileSchema(t)
+	if err != nil {
+		return nil, err
+	}
+	// concurrent compilations of the same type are equivalent; keep the first one stored
+	cached, _ := p.cache.LoadOrStore(t, s)
+	return cached.(*Schema), nil
+}
+
+// discard compiled schemas, as they depend on the registered parsers and reserved types
+func (p *Parser) resetCache() {
+	p.cache.Range(func(key, _ any) bool {
+		p.cache.Delete(key)
+		return true
+	})
+}
+
+// Check if struct type is reserved, has a custom parser or parses its own values, and should not be recursively
+// parsed
+func (p *Parser) isReserved(t reflect.Type) bool {
+	return p.hasParser(t) || p.reserved[t.String()]
+}
+
+// check if type has a custom parser, is a registered enum, or parses its own values
+func (p *Parser) hasParser(t reflect.Type) bool {
+	if _, ok := p.typeParsers[t]; ok {
+		return true
+	}
+	if _, ok := p.enums[t]; ok {
+		return true
+	}
+	if _, ok := p.parsers[t.String()]; ok {
+		return true
+	}
+	return isUnmarshaler(t)
+}
+
+// check if type is a slice or map, converted item by item
+func (p *Parser) isMulti(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Map) && !p.hasParser(t)
+}
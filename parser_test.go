@@ -0,0 +1,181 @@
+package argv
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type parserLevel int
+
+type ParserStruct struct {
+	Name  string      `cli:"name" argv:"other"`
+	Level parserLevel `cli:"level,optional"`
+}
+
+func parseLevel(in string) (any, error) {
+	switch in {
+	case "low":
+		return parserLevel(1), nil
+	case "high":
+		return parserLevel(2), nil
+	}
+	return nil, fmt.Errorf("invalid level '%s'", in)
+}
+
+func TestNewParser(t *testing.T) {
+	p := NewParser(WithTagName("cli"), WithFieldParser("argv.parserLevel", parseLevel))
+
+	var dest ParserStruct
+	assert.Nil(t, p.ParseArgv(&dest, []string{"--name", "job", "--level", "high"}))
+	assert.Equal(t, ParserStruct{Name: "job", Level: 2}, dest)
+
+	names, err := p.ParseNames(&ParserStruct{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"name", "level"}, names)
+
+	// registries are not shared with the default parser
+	names, err = ParseNames(&ParserStruct{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"other"}, names)
+	err = NewParser(WithTagName("cli")).ParseArgv(&dest, []string{"--name", "job", "--level", "high"})
+	assert.Equal(t, ErrTypeInvalidValue, err.(FieldError).ErrorType)
+
+	err = p.ParseArgv(&dest, []string{"--name", "job", "--level", "medium"})
+	assert.Equal(t, ErrInvalidValue("level", fmt.Errorf("invalid level 'medium'")), err)
+}
+
+func TestParserOptions(t *testing.T) {
+	p := NewParser(WithTagName("cli"), WithStrict(false))
+
+	// parser options are used as defaults, and may be overridden per call
+	var dest ParserStruct
+	assert.Nil(t, p.ParseArgv(&dest, []string{"--name", "job", "--unknown"}))
+	err := p.ParseArgv(&dest, []string{"--name", "job", "--unknown"}, WithStrict(true))
+	assert.Equal(t, ErrUnknownArgs([]string{"unknown"}, map[string][]string{}), err)
+
+	p = NewParser(WithTagName("cli"), WithFieldParser("argv.parserLevel", parseLevel), WithEnvPrefix("JOB_"))
+	assert.Nil(t, p.ParseArgv(&dest, []string{"--level", "low"}, WithEnvLookup(envMap(map[string]string{"JOB_NAME": "env"}))))
+	assert.Equal(t, "env", dest.Name)
+}
+
+func TestParserCommand(t *testing.T) {
+	p := NewParser(WithTagName("cli"), WithFieldParser("argv.parserLevel", parseLevel))
+	var dest ParserStruct
+	root := NewCommand("tool", &dest, func() error { return nil }).WithParser(p)
+	assert.Nil(t, root.Execute([]string{"--name", "job", "--level", "low"}))
+	assert.Equal(t, parserLevel(1), dest.Level)
+
+	usage, err := root.Usage(WithWidth(70))
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(usage, "--level"), usage)
+}
+
+func TestParserConcurrentRegistration(t *testing.T) {
+	p := NewParser(WithTagName("cli"))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			p.AddParser("argv.parserLevel", parseLevel)
+			p.AddReservedType("time.Location")
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = p.ParseNames(&ParserStruct{})
+		}()
+	}
+	wg.Wait()
+
+	var dest ParserStruct
+	assert.Nil(t, p.ParseArgv(&dest, []string{"--name", "job", "--level", "low"}))
+	assert.Equal(t, parserLevel(1), dest.Level)
+}
+
+type parserPair[T any] struct {
+	Key   string
+	Value T
+}
+
+type parserID struct {
+	Prefix string
+	Serial int
+}
+
+type ParserTypedStruct struct {
+	ID    parserID           `argv:"id"`
+	Pair  parserPair[int]    `argv:"pair,optional"`
+	Pairs parserPair[string] `argv:"pairs,optional"`
+	When  time.Time          `argv:"when,optional"`
+	Level parserLevel        `argv:"level,optional"`
+	Any   fmt.Stringer       `argv:"any,optional"`
+}
+
+func parseID(in string) (parserID, error) {
+	prefix, serial, ok := strings.Cut(in, "-")
+	if !ok {
+		return parserID{}, fmt.Errorf("invalid id '%s'", in)
+	}
+	n, err := strconv.Atoi(serial)
+	return parserID{Prefix: prefix, Serial: n}, err
+}
+
+func TestRegisterParser(t *testing.T) {
+	p := NewParser(
+		WithTypeParser(parseID),
+		WithTypeParser(func(in string) (parserPair[int], error) {
+			n, err := strconv.Atoi(in)
+			return parserPair[int]{Key: "int", Value: n}, err
+		}),
+		WithTypeParser(func(in string) (parserPair[string], error) {
+			return parserPair[string]{Key: "string", Value: in}, nil
+		}),
+		// typed parsers take precedence over built-in conversions
+		WithTypeParser(func(in string) (time.Time, error) {
+			return time.Parse(time.DateOnly, in)
+		}),
+		WithTypeParser(func(in string) (fmt.Stringer, error) {
+			return time.Duration(len(in)), nil
+		}),
+	)
+
+	// struct types with a parser are not recursively parsed
+	names, err := p.ParseNames(&ParserTypedStruct{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "pair", "pairs", "when", "level", "any"}, names)
+
+	var dest ParserTypedStruct
+	err = p.ParseArgv(&dest, []string{"--id", "job-12", "--pair", "3", "--pairs", "x", "--when", "2024-03-01", "--any", "abc"})
+	assert.Nil(t, err)
+	assert.Equal(t, parserID{Prefix: "job", Serial: 12}, dest.ID)
+	assert.Equal(t, parserPair[int]{Key: "int", Value: 3}, dest.Pair)
+	assert.Equal(t, parserPair[string]{Key: "string", Value: "x"}, dest.Pairs)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), dest.When)
+	assert.Equal(t, time.Duration(3), dest.Any)
+
+	err = p.ParseArgv(&dest, []string{"--id", "job"})
+	assert.Equal(t, ErrInvalidValue("id", fmt.Errorf("invalid id 'job'")), err)
+
+	// default parser
+	RegisterParser(parseID)
+	var other struct {
+		ID parserID `argv:"id"`
+	}
+	assert.Nil(t, ParseArgv(&other, []string{"--id", "a-1"}))
+	assert.Equal(t, parserID{Prefix: "a", Serial: 1}, other.ID)
+}
+
+func TestAddParserInvalidType(t *testing.T) {
+	// string-keyed parsers returning the wrong type fail instead of panicking
+	p := NewParser(WithTagName("cli"), WithFieldParser("argv.parserLevel", func(in string) (any, error) {
+		return in, nil
+	}))
+	var dest ParserStruct
+	err := p.ParseArgv(&dest, []string{"--name", "x", "--level", "low"})
+	assert.Equal(t, ErrInvalidValue("level", fmt.Errorf("parser for argv.parserLevel returned string")), err)
+}
@@ -0,0 +1,67 @@
+package argv
+
+// Source identifies how a field value was assigned
+type Source int
+
+const (
+	SourceNone    Source = iota // field was not assigned
+	SourceDefault               // default value, declared in the tag
+	SourceEnv                   // environment variable
+	SourceArg                   // command-line argument, named or positional
+)
+
+// ParseResult reports which fields were assigned by Parse, and how
+type ParseResult struct {
+	root    string
+	paths   map[string]bool   // full paths of the declared fields
+	sources map[string]Source // by full path
+}
+
+func newParseResult(s *Schema) *ParseResult {
+	root := ""
+	if s.Type != nil {
+		root = s.Type.Name()
+	}
+	paths := make(map[string]bool, len(s.Fields))
+	for _, spec := range s.Fields {
+		paths[spec.Path] = true
+	}
+	return &ParseResult{
+		root:    root,
+		paths:   paths,
+		sources: make(map[string]Source, len(s.Fields)),
+	}
+}
+
+// record the source of a field value
+func (r *ParseResult) set(spec *FieldSpec, source Source) {
+	r.sources[spec.Path] = source
+}
+
+// check if a field was explicitly supplied
+func (r *ParseResult) isSet(spec *FieldSpec) bool {
+	source := r.sources[spec.Path]
+	return source == SourceArg || source == SourceEnv
+}
+
+// full field path, including the destination struct name; paths naming a declared field are already full
+func (r *ParseResult) full(fieldPath string) string {
+	if len(r.root) == 0 || r.paths[fieldPath] {
+		return fieldPath
+	}
+	return r.root + "." + fieldPath
+}
+
+// Source returns how a field was assigned; fieldPath is the struct field path relative to the destination, e.g.
+// "Algorithm.KeyLen", or the full FieldSpec.Path, e.g. "CertInfo.Algorithm.KeyLen"; if a path is valid in both
+// forms, the full path takes precedence
+func (r *ParseResult) Source(fieldPath string) Source {
+	return r.sources[r.full(fieldPath)]
+}
+
+// IsSet returns true if a field was explicitly supplied, either as argument or environment variable
+// Fields assigned from their default value, or not assigned at all, are not set; see Source.
+func (r *ParseResult) IsSet(fieldPath string) bool {
+	source := r.Source(fieldPath)
+	return source == SourceArg || source == SourceEnv
+}
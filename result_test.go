@@ -0,0 +1,107 @@
+package argv
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type ResultAlgorithm struct {
+	Name   string `argv:"alg,default=rsa"`
+	KeyLen *uint  `argv:"bits,optional"`
+}
+
+type ResultOutput struct {
+	Path string `argv:"out,optional"`
+}
+
+type ResultCertInfo struct {
+	Domain    string     `argv:"#0"`
+	Days      *uint32    `argv:"days,optional,env=RESULT_DAYS"`
+	Verbose   *bool      `argv:"verbose|v,optional"`
+	NotBefore *time.Time `argv:"not-before,optional"`
+	Algorithm *ResultAlgorithm
+	Output    *ResultOutput
+}
+
+type ResultRecursive struct {
+	Name string `argv:"name"`
+	Next *ResultRecursive
+}
+
+type ResultConfigInner struct {
+	X int `argv:"x,optional"`
+}
+
+// nested field named like the destination type, so relative and full paths overlap
+type ResultConfig struct {
+	Verbose      bool `argv:"verbose,optional"`
+	ResultConfig ResultConfigInner
+}
+
+func TestParse(t *testing.T) {
+	env := WithEnvLookup(envMap(map[string]string{"RESULT_DAYS": "30"}))
+
+	dest := &ResultCertInfo{}
+	result, err := Parse(dest, []string{"example.com", "-v", "--bits", "4096", "--not-before", "2024-03-01T00:00:00Z"}, env)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(30), *dest.Days)
+	assert.True(t, *dest.Verbose)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), *dest.NotBefore)
+	assert.Equal(t, "rsa", dest.Algorithm.Name)
+	assert.Equal(t, uint(4096), *dest.Algorithm.KeyLen)
+	// nested struct pointers are only allocated if a field is assigned
+	assert.Nil(t, dest.Output)
+
+	assert.Equal(t, SourceArg, result.Source("Domain"))
+	assert.Equal(t, SourceEnv, result.Source("Days"))
+	assert.Equal(t, SourceArg, result.Source("Algorithm.KeyLen"))
+	assert.Equal(t, SourceArg, result.Source("ResultCertInfo.Algorithm.KeyLen"))
+	assert.Equal(t, SourceDefault, result.Source("Algorithm.Name"))
+	assert.Equal(t, SourceNone, result.Source("Output.Path"))
+	assert.True(t, result.IsSet("Days"))
+	assert.True(t, result.IsSet("Verbose"))
+	assert.False(t, result.IsSet("Algorithm.Name"))
+	assert.False(t, result.IsSet("Output.Path"))
+
+	// omitted pointer fields remain nil
+	dest = &ResultCertInfo{}
+	result, err = Parse(dest, []string{"example.com", "--out", "cert.pem"})
+	assert.Nil(t, err)
+	assert.Nil(t, dest.Days)
+	assert.Nil(t, dest.Verbose)
+	assert.Nil(t, dest.NotBefore)
+	// defaults alone do not allocate nested struct pointers
+	assert.Nil(t, dest.Algorithm)
+	assert.Equal(t, SourceNone, result.Source("Algorithm.Name"))
+	assert.Equal(t, "cert.pem", dest.Output.Path)
+	assert.False(t, result.IsSet("Days"))
+	assert.True(t, result.IsSet("Output.Path"))
+
+	// zero values are distinguishable from omitted args
+	dest = &ResultCertInfo{}
+	_, err = Parse(dest, []string{"example.com", "--days", "0", "--verbose=false"})
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(0), *dest.Days)
+	assert.False(t, *dest.Verbose)
+
+	// anonymous destination structs have no name to strip from paths
+	anonymous := &struct {
+		Count int `argv:"count,optional"`
+	}{}
+	result, err = Parse(anonymous, []string{"--count", "3"})
+	assert.Nil(t, err)
+	assert.True(t, result.IsSet("Count"))
+
+	result, err = Parse(&ResultConfig{}, []string{"-x", "1"})
+	assert.Nil(t, err)
+	assert.True(t, result.IsSet("ResultConfig.X"))
+	assert.True(t, result.IsSet("ResultConfig.ResultConfig.X"))
+	assert.False(t, result.IsSet("Verbose"))
+	assert.False(t, result.IsSet("ResultConfig.Verbose"))
+
+	_, err = Parse(dest, []string{"example.com", "--days", "x"})
+	assert.EqualError(t, err, "error parsing arg days: strconv.ParseUint: parsing \"x\": invalid syntax")
+	_, err = Parse(&ResultRecursive{}, []string{"--name", "x"})
+	assert.EqualError(t, err, "invalid argv tag on field ResultRecursive.Next: recursive nested struct argv.ResultRecursive")
+}
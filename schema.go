@@ -0,0 +1,508 @@
+package argv
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	positionalPrefix = "#"
+	positionalRest   = "#rest"
+	extraArgs        = "#extra"
+	notPositional    = -1
+)
+
+// FieldSpec describes the argument bound to a tagged struct field
+type FieldSpec struct {
+	Name          string       // primary name; for positional fields, the slot tag, e.g. "#0" or "#rest"
+	Names         []string     // all names, including aliases, as declared
+	Type          reflect.Type // field type
+	Index         []int        // field index path, relative to the destination struct; may cross nested struct pointers
+	Field         string       // struct field name
+	Path          string       // struct and field path, e.g. "CertInfo.Algorithm.KeyLen"
+	Struct        reflect.Type // struct type declaring the field
+	Group         string       // nested struct path, e.g. "Algorithm"; empty for top-level fields
+	Optional      bool         // argument may be omitted; always true if a default is declared
+	Default       string       // default value, if HasDefault
+	HasDefault    bool
+	Env           string   // environment variable name, if declared
+	Help          string   // description, for usage output
+	Counter       bool     // integer field counting occurrences, e.g. "-vvv"
+	Position      int      // positional slot, or -1 for named args
+	Rest          bool     // variadic positional, receives all remaining positional values
+	Layout        string   // time layout, as declared, for time.Time fields
+	TimeZone      string   // time zone name, as declared, for time.Time fields
+	Unit          string   // unit suffixes accepted by numeric fields, UnitBytes or UnitSI; empty for none
+	Choices       []string // valid values, declared with choices= or registered with RegisterEnum; nil for any
+	IgnoreCase    bool     // choices are matched case-insensitively
+	Rules         []string // validation rules, as declared, e.g. "min=2048"
+	Separator     string   // item separator, for slice and map fields; empty if values are not split
+	DuplicateKeys string   // duplicate key policy, for map fields
+	multi         bool     // slice or map field, receiving the items of all values
+	set           setter   // value conversion, resolved on compilation
+	setElem       setter   // item conversion, for slice and map fields
+	setKey        setter   // key conversion, for map fields
+	rules         []rule   // validation rules, checked after conversion
+}
+
+// duplicate key policies for map fields, set with the dupkeys= tag option
+const (
+	DuplicateKeysLast  = "last" // default; the last value is kept
+	DuplicateKeysFirst = "first"
+	DuplicateKeysError = "error"
+)
+
+// Schema describes the arguments of a destination struct
+// Schemas are compiled once per type and shared, and must not be modified.
+type Schema struct {
+	Type        reflect.Type  // destination struct type
+	Fields      []*FieldSpec  // all tagged fields, named and positional, in declaration order
+	Positional  []*FieldSpec  // positional fields, ordered by slot
+	Rest        *FieldSpec    // #rest field, if any
+	Extra       *FieldSpec    // #extra field, receiving unknown arguments, if any
+	Constraints []*Constraint // cross-field constraints, in declaration order
+	named       map[string]*FieldSpec
+	parent      *Schema // parent command schema, providing global named args
+}
+
+// ParseSchema returns the argument schema of dest, a pointer to a tagged struct
+func ParseSchema(dest any) (*Schema, error) {
+	return defaultParser.ParseSchema(dest)
+}
+
+// Lookup returns the named field matching name, including aliases; returns nil if not found
+func (s *Schema) Lookup(name string) *FieldSpec {
+	return s.lookup(name, 0)
+}
+
+// Named returns the named fields, in declaration order
+func (s *Schema) Named() []*FieldSpec {
+	result := make([]*FieldSpec, 0, len(s.Fields))
+	for _, spec := range s.Fields {
+		if !spec.IsPositional() {
+			result = append(result, spec)
+		}
+	}
+	return result
+}
+
+// Groups returns the nested struct groups declaring named fields, in declaration order; the top-level group
+// is an empty string
+func (s *Schema) Groups() []string {
+	result := make([]string, 0)
+	seen := make(map[string]bool, 0)
+	for _, spec := range s.Named() {
+		if !seen[spec.Group] {
+			seen[spec.Group] = true
+			result = append(result, spec.Group)
+		}
+	}
+	return result
+}
+
+// compileSchema walks the struct type and collects the argument specification
+func (p *Parser) compileSchema(t reflect.Type) (*Schema, error) {
+	s := &Schema{
+		Type:       t,
+		Fields:     make([]*FieldSpec, 0),
+		Positional: make([]*FieldSpec, 0),
+		named:      make(map[string]*FieldSpec, 0),
+	}
+	if err := s.walk(p, t, nil, t.Name(), "", []reflect.Type{t}); err != nil {
+		return nil, err
+	}
+	if err := s.sortPositional(); err != nil {
+		return nil, err
+	}
+	if err := s.compileConstraints(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// walk collects the tagged fields of struct type t; parents are the enclosing struct types, to detect recursion
+func (s *Schema) walk(p *Parser, t reflect.Type, index []int, prefix string, group string, parents []reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldIndex := append(append(make([]int, 0, len(index)+1), index...), i)
+		path := field.Name
+		if len(prefix) > 0 {
+			// anonymous destination structs have no name, and their paths no prefix
+			path = prefix + "." + field.Name
+		}
+		// nested structs, and pointers to structs, are parsed recursively
+		nested := field.Type
+		if nested.Kind() == reflect.Ptr {
+			nested = nested.Elem()
+		}
+		if nested.Kind() == reflect.Struct && !p.isReserved(field.Type) && !p.isReserved(nested) {
+			for _, parent := range parents {
+				if parent == nested {
+					return ErrInvalidTag(path, fmt.Errorf("recursive nested struct %s", nested.String()))
+				}
+			}
+			fieldGroup := field.Name
+			if len(group) > 0 {
+				fieldGroup = group + "." + field.Name
+			}
+			if err := s.walk(p, nested, fieldIndex, path, fieldGroup, append(parents, nested)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, err := lookupTag(field.Tag, p.tagName)
+		if err != nil {
+			return ErrInvalidTag(path, err)
+		}
+		tag, err := parseTag(raw)
+		if err != nil {
+			return ErrInvalidTag(path, err)
+		}
+		fieldName := tag.name
+		if len(fieldName) == 0 {
+			continue
+		}
+		defValue, hasDefault := tag.get("default")
+		env, _ := tag.get("env")
+		help, _ := tag.get("help")
+		if tag.has("counter") && !isIntKind(field.Type.Kind()) {
+			return ErrInvalidTag(path, fmt.Errorf("counter requires an integer field"))
+		}
+		sep, hasSep := tag.get("sep")
+		if hasSep && !p.isMulti(field.Type) {
+			return ErrInvalidTag(path, fmt.Errorf("sep requires a slice or map field"))
+		}
+		dupKeys, hasDupKeys := tag.get("dupkeys")
+		if hasDupKeys && (!p.isMulti(field.Type) || field.Type.Kind() != reflect.Map) {
+			return ErrInvalidTag(path, fmt.Errorf("dupkeys requires a map field"))
+		}
+		switch dupKeys {
+		case "", DuplicateKeysLast, DuplicateKeysFirst, DuplicateKeysError:
+		default:
+			return ErrInvalidTag(path, fmt.Errorf("invalid dupkeys policy '%s'", dupKeys))
+		}
+		layout, _ := tag.get("layout")
+		tz, _ := tag.get("tz")
+		opts, err := parseValueOptions(tag, field.Type)
+		if err != nil {
+			return ErrInvalidTag(path, err)
+		}
+		if strings.Contains(sep, `"`) {
+			return ErrInvalidTag(path, fmt.Errorf("invalid separator '%s'", sep))
+		}
+		choices, err := p.fieldChoices(tag, field.Type)
+		if err != nil {
+			return ErrInvalidTag(path, err)
+		}
+		names, err := splitNames(fieldName)
+		if err != nil {
+			return ErrInvalidTag(path, err)
+		}
+		spec := &FieldSpec{
+			Name:       primaryName(names),
+			Names:      names,
+			Type:       field.Type,
+			Index:      fieldIndex,
+			Field:      field.Name,
+			Path:       path,
+			Struct:     t,
+			Group:      group,
+			Optional:   tag.has("optional") || hasDefault,
+			Default:    defValue,
+			HasDefault: hasDefault,
+			Env:        env,
+			Help:       help,
+			Counter:    tag.has("counter"),
+			Layout:     layout,
+			TimeZone:   tz,
+			Unit:       opts.unit,
+			Choices:    choices,
+			IgnoreCase: opts.ignoreCase,
+			Position:   notPositional,
+		}
+		if p.isMulti(field.Type) {
+			spec.multi = true
+			spec.Separator = defaultSeparator
+			if hasSep {
+				spec.Separator = sep
+			}
+			spec.setElem = p.newSetter(field.Type.Elem(), opts)
+			if field.Type.Kind() == reflect.Map {
+				spec.DuplicateKeys = DuplicateKeysLast
+				if hasDupKeys {
+					spec.DuplicateKeys = dupKeys
+				}
+				spec.setKey = p.newSetter(field.Type.Key(), opts)
+			}
+		} else {
+			spec.set = p.newSetter(field.Type, opts)
+		}
+		if spec.rules, err = p.fieldRules(tag, spec, opts); err != nil {
+			return ErrInvalidTag(path, err)
+		}
+		for _, r := range spec.rules {
+			spec.Rules = append(spec.Rules, r.String())
+		}
+		// enum setters check their own names
+		if tag.has("choices") {
+			if spec.multi {
+				spec.setElem = choiceSetter(spec.setElem, choices, opts.ignoreCase)
+			} else {
+				spec.set = choiceSetter(spec.set, choices, opts.ignoreCase)
+			}
+		}
+		if hasDefault {
+			if err := spec.checkDefault(); err != nil {
+				return ErrInvalidTag(path, fmt.Errorf("invalid default value: %w", err))
+			}
+		}
+		if fieldName == extraArgs {
+			if s.Extra != nil {
+				return ErrInvalidTag(spec.Path, fmt.Errorf("duplicate %s field", extraArgs))
+			}
+			if !isExtraType(spec.Type) {
+				return ErrInvalidTag(spec.Path, fmt.Errorf("%s requires a map[string]string or []string field", extraArgs))
+			}
+			s.Extra = spec
+			continue
+		}
+		if strings.HasPrefix(fieldName, positionalPrefix) {
+			if tag.has(ConstraintExclusive) || tag.has(ConstraintTogether) || tag.has(ConstraintRequiredIf) {
+				return ErrInvalidTag(spec.Path, fmt.Errorf("constraints are not supported on positional fields"))
+			}
+			if len(names) > 1 {
+				return ErrInvalidTag(spec.Path, fmt.Errorf("aliases are not supported on positional fields"))
+			}
+			if err := spec.parsePosition(); err != nil {
+				return ErrInvalidTag(spec.Path, err)
+			}
+			if len(spec.Env) > 0 {
+				return ErrInvalidTag(spec.Path, fmt.Errorf("env is not supported on positional fields"))
+			}
+			if spec.Rest {
+				if s.Rest != nil {
+					return ErrInvalidTag(spec.Path, fmt.Errorf("duplicate %s field", positionalRest))
+				}
+				s.Rest = spec
+			} else {
+				s.Positional = append(s.Positional, spec)
+			}
+		} else {
+			for _, name := range names {
+				if other, ok := s.named[name]; ok {
+					return ErrInvalidTag(spec.Path, fmt.Errorf("duplicate argument name '%s', also used by %s", name, other.Path))
+				}
+				s.named[name] = spec
+			}
+			if err := s.addConstraints(tag, spec); err != nil {
+				return ErrInvalidTag(spec.Path, err)
+			}
+		}
+		s.Fields = append(s.Fields, spec)
+	}
+	return nil
+}
+
+// check if type is valid for an #extra field
+func isExtraType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+// parse positional tag names, such as "#0" or "#rest"
+func (f *FieldSpec) parsePosition() error {
+	if f.Name == positionalRest {
+		if f.Type.Kind() != reflect.Slice {
+			return fmt.Errorf("%s requires a slice field", positionalRest)
+		}
+		f.Rest = true
+		return nil
+	}
+	pos, err := strconv.Atoi(f.Name[len(positionalPrefix):])
+	if err != nil || pos < 0 {
+		return fmt.Errorf("invalid positional index '%s'", f.Name)
+	}
+	f.Position = pos
+	return nil
+}
+
+// order positional fields and check slots are contiguous, with no required slot after an optional one
+func (s *Schema) sortPositional() error {
+	result := make([]*FieldSpec, len(s.Positional))
+	for _, spec := range s.Positional {
+		if spec.Position >= len(result) {
+			return ErrInvalidTag(spec.Path, fmt.Errorf("positional slots must be contiguous, starting at #0"))
+		}
+		if result[spec.Position] != nil {
+			return ErrInvalidTag(spec.Path, fmt.Errorf("duplicate positional slot"))
+		}
+		result[spec.Position] = spec
+	}
+	optional := false
+	for _, spec := range result {
+		if optional && !spec.Optional {
+			return ErrInvalidTag(spec.Path, fmt.Errorf("required positional slot after optional slot"))
+		}
+		optional = spec.Optional
+	}
+	if optional && s.Rest != nil && !s.Rest.Optional {
+		return ErrInvalidTag(s.Rest.Path, fmt.Errorf("required positional slot after optional slot"))
+	}
+	s.Positional = result
+	return nil
+}
+
+// check if destination accepts positional arguments
+func (s *Schema) hasPositional() bool {
+	return len(s.Positional) > 0 || s.Rest != nil
+}
+
+// empty schema, for commands without destination
+func emptySchema() *Schema {
+	return &Schema{
+		Fields:     make([]*FieldSpec, 0),
+		Positional: make([]*FieldSpec, 0),
+		named:      make(map[string]*FieldSpec, 0),
+	}
+}
+
+// copy of the schema, with named args from parent available as global args
+func (s *Schema) withParent(parent *Schema) *Schema {
+	result := *s
+	result.parent = parent
+	return &result
+}
+
+// names of all named args, including aliases and global args, in declaration order
+func (s *Schema) names() []string {
+	result := make([]string, 0, len(s.named))
+	for _, spec := range s.Fields {
+		if !spec.IsPositional() {
+			result = append(result, spec.Names...)
+		}
+	}
+	if s.parent != nil {
+		result = append(result, s.parent.names()...)
+	}
+	return result
+}
+
+// resolve a named arg, including global args; short names only match when used with a single dash
+func (s *Schema) lookup(name string, dashes int) *FieldSpec {
+	if dashes == 2 && isShortName(name) {
+		return nil
+	}
+	if spec, ok := s.named[name]; ok {
+		return spec
+	}
+	if s.parent != nil {
+		return s.parent.lookup(name, dashes)
+	}
+	return nil
+}
+
+// find the schema declaring a given field, either s or one of its parents
+func (s *Schema) owner(spec *FieldSpec) *Schema {
+	for current := s; current != nil; current = current.parent {
+		if current.named[spec.Name] == spec {
+			return current
+		}
+	}
+	return nil
+}
+
+// Aliases returns the field names, excluding the primary name
+func (f *FieldSpec) Aliases() []string {
+	result := make([]string, 0, len(f.Names))
+	for _, name := range f.Names {
+		if name != f.Name {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// IsPositional returns true for positional fields, including #rest
+func (f *FieldSpec) IsPositional() bool {
+	return f.Position != notPositional || f.Rest
+}
+
+// IsList returns true for slice fields, which may be repeated, e.g. "-tag a -tag b"; see Separator
+func (f *FieldSpec) IsList() bool {
+	return f.multi && f.Type.Kind() == reflect.Slice
+}
+
+// IsMap returns true for map fields, receiving key=value items, e.g. "-label env=prod -label tier=web"
+func (f *FieldSpec) IsMap() bool {
+	return f.multi && f.Type.Kind() == reflect.Map
+}
+
+// IsFlag returns true if field is boolean, or a pointer to boolean, or a counter, and may be used without value
+func (f *FieldSpec) IsFlag() bool {
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return f.Counter || t.Kind() == reflect.Bool
+}
+
+// field value in the destination struct v, allocating nil nested struct pointers
+func (f *FieldSpec) field(v reflect.Value) reflect.Value {
+	for i, index := range f.Index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(index)
+	}
+	return v
+}
+
+// field value in the destination struct v, without allocating nested struct pointers; returns false if any is nil
+func (f *FieldSpec) allocatedField(v reflect.Value) (reflect.Value, bool) {
+	for i, index := range f.Index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(index)
+	}
+	return v, true
+}
+
+// value type of pointer, slice and map types, e.g. time.Time for []*time.Time
+func baseType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+// check if kind is a signed or unsigned integer
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package argv
+
+import (
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+)
+
+type SchemaAlgorithm struct {
+	Algorithm string `argv:"alg,help=key algorithm"`
+	KeyLen    uint   `argv:"bits|b,default=2048"`
+}
+
+type SchemaCertInfo struct {
+	Domain    string `argv:"#0"`
+	Days      uint32 `argv:"days|d,optional,env=CERT_DAYS"`
+	Algorithm SchemaAlgorithm
+	Files     []string          `argv:"#rest,optional"`
+	Extra     map[string]string `argv:"#extra"`
+	ignored   string            `argv:"ignored"`
+}
+
+// copy of spec without the compiled setters and rules, which are not comparable
+func exportedSpec(spec *FieldSpec) *FieldSpec {
+	result := *spec
+	result.set = nil
+	result.setElem = nil
+	result.setKey = nil
+	result.rules = nil
+	return &result
+}
+
+func TestParseSchema(t *testing.T) {
+	s, err := ParseSchema(&SchemaCertInfo{})
+	assert.Nil(t, err)
+	assert.Equal(t, reflect.TypeOf(SchemaCertInfo{}), s.Type)
+	assert.Len(t, s.Fields, 5)
+
+	assert.Equal(t, &FieldSpec{
+		Name:     "#0",
+		Names:    []string{"#0"},
+		Type:     reflect.TypeOf(""),
+		Index:    []int{0},
+		Field:    "Domain",
+		Path:     "SchemaCertInfo.Domain",
+		Struct:   reflect.TypeOf(SchemaCertInfo{}),
+		Position: 0,
+	}, exportedSpec(s.Fields[0]))
+	assert.Equal(t, []*FieldSpec{s.Fields[0]}, s.Positional)
+	assert.Equal(t, s.Fields[4], s.Rest)
+	assert.True(t, s.Rest.IsPositional())
+	assert.Equal(t, "Extra", s.Extra.Field)
+
+	days := s.Lookup("d")
+	assert.Equal(t, &FieldSpec{
+		Name:     "days",
+		Names:    []string{"days", "d"},
+		Type:     reflect.TypeOf(uint32(0)),
+		Index:    []int{1},
+		Field:    "Days",
+		Path:     "SchemaCertInfo.Days",
+		Struct:   reflect.TypeOf(SchemaCertInfo{}),
+		Optional: true,
+		Env:      "CERT_DAYS",
+		Position: -1,
+	}, exportedSpec(days))
+	assert.Equal(t, []string{"d"}, days.Aliases())
+	assert.False(t, days.IsFlag())
+
+	bits := s.Lookup("bits")
+	assert.Equal(t, &FieldSpec{
+		Name:       "bits",
+		Names:      []string{"bits", "b"},
+		Type:       reflect.TypeOf(uint(0)),
+		Index:      []int{2, 1},
+		Field:      "KeyLen",
+		Path:       "SchemaCertInfo.Algorithm.KeyLen",
+		Struct:     reflect.TypeOf(SchemaAlgorithm{}),
+		Group:      "Algorithm",
+		Optional:   true,
+		Default:    "2048",
+		HasDefault: true,
+		Position:   -1,
+	}, exportedSpec(bits))
+	assert.Equal(t, s.Lookup("b"), bits)
+	assert.Nil(t, s.Lookup("ignored"))
+	assert.Nil(t, s.Lookup("#0"))
+
+	assert.Equal(t, []*FieldSpec{days, s.Lookup("alg"), bits}, s.Named())
+	assert.Equal(t, []string{"", "Algorithm"}, s.Groups())
+
+	_, err = ParseSchema(SchemaCertInfo{})
+	assert.ErrorIs(t, err, ErrInvalidDest)
+}
+
+func TestParseSchemaCache(t *testing.T) {
+	s1, err := ParseSchema(&SchemaCertInfo{})
+	assert.Nil(t, err)
+	s2, err := ParseSchema(&SchemaCertInfo{})
+	assert.Nil(t, err)
+	assert.Same(t, s1, s2)
+
+	// registering parsers or reserved types discards compiled schemas
+	p := NewParser()
+	s3, err := p.ParseSchema(&SchemaCertInfo{})
+	assert.Nil(t, err)
+	assert.NotSame(t, s1, s3)
+	assert.NotNil(t, s3.Lookup("bits"))
+
+	p.AddReservedType("argv.SchemaAlgorithm")
+	s4, err := p.ParseSchema(&SchemaCertInfo{})
+	assert.Nil(t, err)
+	assert.NotSame(t, s3, s4)
+	assert.Nil(t, s4.Lookup("bits"))
+
+	s5, err := ParseSchema(&SchemaCertInfo{})
+	assert.Nil(t, err)
+	assert.Same(t, s1, s5)
+}
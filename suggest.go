@@ -0,0 +1,81 @@
+package argv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	maxSuggestions = 3
+)
+
+// suggest returns the candidates closest to name, ranked by edit distance
+// candidates further away than a third of the name length (min. 1) are discarded, as well as exact matches
+// and short names, as any single character would be suggested
+func suggest(name string, candidates []string) []string {
+	maxDistance := (len(name) + 2) / 3
+	type match struct {
+		name     string
+		distance int
+	}
+	matches := make([]match, 0)
+	for _, c := range candidates {
+		if c == name || isShortName(c) {
+			continue
+		}
+		if d := editDistance(name, c); d <= maxDistance {
+			matches = append(matches, match{name: c, distance: d})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].distance == matches[j].distance {
+			return matches[i].name < matches[j].name
+		}
+		return matches[i].distance < matches[j].distance
+	})
+	result := make([]string, 0, maxSuggestions)
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		result = append(result, matches[i].name)
+	}
+	return result
+}
+
+// editDistance computes the optimal string alignment distance between a and b; this is the Levenshtein
+// distance, with transposition of adjacent characters counted as a single edit
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// format a suggestion list, e.g. "did you mean 'days' or 'day'?"
+func formatSuggestions(suggestions []string) string {
+	quoted := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		quoted[i] = fmt.Sprintf("'%s'", s)
+	}
+	if len(quoted) == 1 {
+		return fmt.Sprintf("did you mean %s?", quoted[0])
+	}
+	return fmt.Sprintf("did you mean %s or %s?", strings.Join(quoted[:len(quoted)-1], ", "), quoted[len(quoted)-1])
+}
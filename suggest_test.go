@@ -0,0 +1,60 @@
+package argv
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type ArgStructSuggest struct {
+	Days      int `argv:"days"`
+	Algorithm struct {
+		Name string `argv:"alg"`
+		Bits uint   `argv:"bits"`
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	testCases := []struct {
+		a        string
+		b        string
+		expected int
+	}{
+		{"", "", 0},
+		{"days", "days", 0},
+		{"dasy", "days", 1},
+		{"day", "days", 1},
+		{"bitz", "bits", 1},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, editDistance(tc.a, tc.b), "%s/%s", tc.a, tc.b)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"days", "day", "alg", "bits", "verbose"}
+	assert.Equal(t, []string{"day", "days"}, suggest("dasy", candidates))
+	assert.Equal(t, []string{"verbose"}, suggest("verbos", candidates))
+	assert.Equal(t, []string{}, suggest("x", []string{"days", "verbose"}))
+}
+
+func TestParseArgvSuggestions(t *testing.T) {
+	err := ParseArgv(&ArgStructSuggest{}, []string{"-alg", "rsa", "-bits", "2048", "-days", "30", "-dasy", "30"})
+	assert.EqualError(t, err, "unknown arg 'dasy'; did you mean 'days'?")
+
+	// typos are reported before the errors they cause, such as a missing required arg
+	err = ParseArgv(&ArgStructSuggest{}, []string{"-alg", "rsa", "-bits", "2048", "-dasy", "30"})
+	assert.EqualError(t, err, "unknown arg 'dasy'; did you mean 'days'?")
+
+	err = ParseArgv(&ValidateStruct{}, []string{"--bits", "1024", "--nmae", "x"})
+	assert.EqualError(t, err, "unknown arg 'nmae'; did you mean 'name'?")
+
+	// nested names are suggested as well
+	err = ParseArgv(&ArgStructSuggest{}, []string{"-alg", "rsa", "-bits", "2048", "-days", "30", "-bitz", "1", "-foo", "x"})
+	assert.EqualError(t, err, "unknown args 'bitz' (did you mean 'bits'?), 'foo'")
+	fieldErr, ok := err.(FieldError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"bitz", "foo"}, fieldErr.Args)
+	assert.Equal(t, map[string][]string{"bitz": {"bits"}}, fieldErr.Suggestions)
+}
@@ -0,0 +1,240 @@
+package argv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	tagSeparator = ','
+	tagAssign    = '='
+	tagQuote     = '\''
+	tagEscape    = '\\'
+	tagAlias     = "|"
+)
+
+// known tag options; the value signals if the option requires a value
+var tagOptions = map[string]bool{
+	"optional": false,
+	"default":  true,
+	"env":      true,
+	"counter":  false,
+	"help":     true,
+	"sep":      true,
+	"dupkeys":  true,
+	"layout":   true,
+	"tz":       true,
+	"unit":     true,
+	"choices":  true,
+	"nocase":   false,
+	"min":      true,
+	"max":      true,
+	"len":      true,
+	"pattern":  true,
+	"nonempty": false,
+
+	"exclusive":  true,
+	"together":   true,
+	"requiredif": true,
+}
+
+// parsed argv tag
+//
+// Tags are composed of an argument name, or several aliases separated by "|", optionally followed by a comma-separated
+// list of options, either as "key" or "key=value". Option values may be single-quoted, e.g. default='a,b'; outside
+// quotes, a backslash escapes the next character, while inside quotes it only escapes a quote, e.g. pattern='\d+'.
+type tagSpec struct {
+	name    string
+	options map[string]string
+}
+
+// check if option is present
+func (t tagSpec) has(option string) bool {
+	_, ok := t.options[option]
+	return ok
+}
+
+// retrieve option value, if present
+func (t tagSpec) get(option string) (string, bool) {
+	v, ok := t.options[option]
+	return v, ok
+}
+
+// lookupTag retrieves the value of the named tag key; keys whose value is not a valid Go string literal, such as
+// `argv:"days,default=a\,b"`, are ignored by reflect.StructTag.Lookup, and are reported as an error instead
+func lookupTag(tag reflect.StructTag, key string) (string, error) {
+	value, ok := tag.Lookup(key)
+	if !ok && strings.Contains(" "+string(tag), " "+key+`:"`) {
+		return "", fmt.Errorf("malformed struct tag, expected a Go string literal; backslashes must be doubled")
+	}
+	return value, nil
+}
+
+// parseTag parses and validates an argv tag
+func parseTag(tag string) (tagSpec, error) {
+	result := tagSpec{
+		options: make(map[string]string, 0),
+	}
+	if len(tag) == 0 {
+		return result, nil
+	}
+	toks, err := splitTag(tag)
+	if err != nil {
+		return result, err
+	}
+	result.name = toks[0].key
+	if toks[0].hasValue || len(result.name) == 0 || strings.ContainsAny(result.name, " \t") {
+		return result, fmt.Errorf("invalid argument name '%s'", tag)
+	}
+	for _, tok := range toks[1:] {
+		requiresValue, ok := tagOptions[tok.key]
+		switch {
+		case !ok:
+			return result, fmt.Errorf("unknown option '%s'", tok.key)
+		case requiresValue && !tok.hasValue:
+			return result, fmt.Errorf("option '%s' requires a value", tok.key)
+		case !requiresValue && tok.hasValue:
+			return result, fmt.Errorf("option '%s' does not take a value", tok.key)
+		}
+		if result.has(tok.key) {
+			return result, fmt.Errorf("duplicate option '%s'", tok.key)
+		}
+		result.options[tok.key] = tok.value
+	}
+	return result, nil
+}
+
+// a single tag item, either the argument name or an option
+type tagToken struct {
+	key      string
+	value    string
+	hasValue bool
+}
+
+// splitTag splits a tag into its items, processing quotes and escapes
+func splitTag(tag string) ([]tagToken, error) {
+	result := make([]tagToken, 0)
+	current := tagToken{}
+	var buf strings.Builder
+	inQuotes := false
+	quoted := false
+
+	flush := func() {
+		if current.hasValue {
+			current.value = buf.String()
+		} else {
+			current.key = strings.TrimSpace(buf.String())
+		}
+		result = append(result, current)
+		current = tagToken{}
+		buf.Reset()
+		quoted = false
+	}
+
+	runes := []rune(tag)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == tagEscape && inQuotes:
+			// inside quotes, backslashes are literal, e.g. in patterns, except before a quote
+			if i+1 < len(runes) && runes[i+1] == tagQuote {
+				i++
+			}
+			buf.WriteRune(runes[i])
+		case r == tagEscape:
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("dangling escape at end of tag")
+			}
+			i++
+			buf.WriteRune(runes[i])
+		case r == tagQuote:
+			if !inQuotes && (!current.hasValue || buf.Len() > 0 || quoted) {
+				return nil, fmt.Errorf("unexpected quote at position %d", i)
+			}
+			inQuotes = !inQuotes
+			quoted = true
+			if !inQuotes && i+1 < len(runes) && runes[i+1] != tagSeparator {
+				return nil, fmt.Errorf("unexpected character after quoted value at position %d", i+1)
+			}
+		case inQuotes:
+			buf.WriteRune(r)
+		case r == tagSeparator:
+			flush()
+		case r == tagAssign && !current.hasValue:
+			current.key = strings.TrimSpace(buf.String())
+			current.hasValue = true
+			buf.Reset()
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	flush()
+	return result, nil
+}
+
+// splitNames splits an argument name into its aliases, e.g. "days|d"
+func splitNames(name string) ([]string, error) {
+	result := strings.Split(name, tagAlias)
+	for _, n := range result {
+		if len(n) == 0 {
+			return nil, fmt.Errorf("empty alias in '%s'", name)
+		}
+	}
+	return result, nil
+}
+
+// primaryName returns the first long name, or the first name if all names are short
+func primaryName(names []string) string {
+	for _, name := range names {
+		if !isShortName(name) {
+			return name
+		}
+	}
+	return names[0]
+}
+
+// check if name is a short, single-character name
+func isShortName(name string) bool {
+	return utf8.RuneCountInString(name) == 1
+}
+
+// parse and validate tag options affecting value conversion, for a field of type t
+func parseValueOptions(tag tagSpec, t reflect.Type) (valueOptions, error) {
+	result := valueOptions{
+		location: time.UTC,
+	}
+	isTime := baseType(t) == timeType
+	if layout, ok := tag.get("layout"); ok {
+		if !isTime {
+			return result, fmt.Errorf("layout requires a time.Time field")
+		}
+		if len(layout) == 0 {
+			return result, fmt.Errorf("empty layout")
+		}
+		result.layout = resolveLayout(layout)
+	}
+	if tz, ok := tag.get("tz"); ok {
+		if !isTime {
+			return result, fmt.Errorf("tz requires a time.Time field")
+		}
+		loc, err := time.LoadLocation(tz)
+		if err != nil || len(tz) == 0 {
+			return result, fmt.Errorf("invalid time zone '%s'", tz)
+		}
+		result.location = loc
+	}
+	if unit, ok := tag.get("unit"); ok {
+		if err := checkUnit(unit, baseType(t)); err != nil {
+			return result, err
+		}
+		result.unit = unit
+	}
+	result.ignoreCase = tag.has("nocase")
+	return result, nil
+}
@@ -0,0 +1,150 @@
+package argv
+
+import (
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+)
+
+type ArgStructTagUnknown struct {
+	Days uint32 `argv:"days,optinal"`
+}
+
+type ArgStructTagQuoted struct {
+	Tags []string `argv:"tags,default='a, b,c'"`
+}
+
+type ArgStructTagEscaped struct {
+	Name string `argv:"name,default=a\\,b" json:"name"`
+}
+
+func TestParseTag(t *testing.T) {
+	testCases := []struct {
+		name     string
+		tag      string
+		expected string
+		result   tagSpec
+	}{
+		{
+			name:   "empty",
+			tag:    "",
+			result: tagSpec{options: map[string]string{}},
+		},
+		{
+			name:   "name only",
+			tag:    "days",
+			result: tagSpec{name: "days", options: map[string]string{}},
+		},
+		{
+			name:   "options",
+			tag:    "days, optional,default=365",
+			result: tagSpec{name: "days", options: map[string]string{"optional": "", "default": "365"}},
+		},
+		{
+			name:   "quoted value",
+			tag:    "days,default='a,b=c',optional",
+			result: tagSpec{name: "days", options: map[string]string{"optional": "", "default": "a,b=c"}},
+		},
+		{
+			name:   "escapes",
+			tag:    `days,default=a\,b\\c`,
+			result: tagSpec{name: "days", options: map[string]string{"default": `a,b\c`}},
+		},
+		{
+			name:   "escaped quote",
+			tag:    `days,default='it\'s'`,
+			result: tagSpec{name: "days", options: map[string]string{"default": "it's"}},
+		},
+		{
+			name:   "literal backslashes in quotes",
+			tag:    `code,pattern='\d{2,4}\.\w+'`,
+			result: tagSpec{name: "code", options: map[string]string{"pattern": `\d{2,4}\.\w+`}},
+		},
+		{
+			name:   "empty quoted value",
+			tag:    `days,default=''`,
+			result: tagSpec{name: "days", options: map[string]string{"default": ""}},
+		},
+		{
+			name:     "unknown option",
+			tag:      "days,potato",
+			expected: "unknown option 'potato'",
+		},
+		{
+			name:     "missing value",
+			tag:      "days,default",
+			expected: "option 'default' requires a value",
+		},
+		{
+			name:     "unexpected value",
+			tag:      "days,optional=true",
+			expected: "option 'optional' does not take a value",
+		},
+		{
+			name:     "duplicate option",
+			tag:      "days,default=1,default=2",
+			expected: "duplicate option 'default'",
+		},
+		{
+			name:     "missing name",
+			tag:      ",optional",
+			expected: "invalid argument name ',optional'",
+		},
+		{
+			name:     "unterminated quote",
+			tag:      "days,default='abc",
+			expected: "unterminated quote",
+		},
+		{
+			name:     "misplaced quote",
+			tag:      "days,default=a'bc'",
+			expected: "unexpected quote at position 14",
+		},
+		{
+			name:     "text after quote",
+			tag:      "days,default='a'b",
+			expected: "unexpected character after quoted value at position 16",
+		},
+		{
+			name:     "dangling escape",
+			tag:      `days,default=a\`,
+			expected: "dangling escape at end of tag",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := parseTag(tc.tag)
+			if len(tc.expected) > 0 {
+				assert.EqualError(t, err, tc.expected)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.result, result)
+		})
+	}
+}
+
+func TestParseArgvTagErrors(t *testing.T) {
+	err := ParseArgv(&ArgStructTagUnknown{}, []string{"-days", "1"})
+	assert.EqualError(t, err, "invalid argv tag on field ArgStructTagUnknown.Days: unknown option 'optinal'")
+
+	_, err = ParseNames(&ArgStructTagUnknown{})
+	assert.EqualError(t, err, "invalid argv tag on field ArgStructTagUnknown.Days: unknown option 'optinal'")
+
+	dest := &ArgStructTagQuoted{}
+	assert.Nil(t, ParseArgv(dest, []string{"--"}))
+	assert.Equal(t, []string{"a", "b", "c"}, dest.Tags)
+
+	// backslashes are doubled inside Go tags
+	escaped := &ArgStructTagEscaped{}
+	assert.Nil(t, ParseArgv(escaped, []string{"--"}))
+	assert.Equal(t, "a,b", escaped.Name)
+
+	// invalid Go escapes are rejected, instead of ignoring the tag; built at runtime, as go vet rejects such tags
+	malformed := reflect.StructOf([]reflect.StructField{
+		{Name: "Days", Type: reflect.TypeOf(""), Tag: `argv:"days,default=a\,b"`},
+	})
+	_, err = ParseSchema(reflect.New(malformed).Interface())
+	assert.EqualError(t, err, "invalid argv tag on field Days: malformed struct tag, expected a Go string literal; backslashes must be doubled")
+}
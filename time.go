@@ -0,0 +1,208 @@
+package argv
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	timeNow      = "now"
+	layoutUnix   = "unix"
+	layoutUnixMs = "unixmilli"
+	day          = 24 * time.Hour
+	week         = 7 * day
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	locationType = reflect.TypeOf(&time.Location{})
+
+	// layouts available by name in the layout= tag option
+	namedLayouts = map[string]string{
+		"RFC3339":     time.RFC3339,
+		"RFC3339Nano": time.RFC3339Nano,
+		"RFC1123":     time.RFC1123,
+		"RFC1123Z":    time.RFC1123Z,
+		"RFC822":      time.RFC822,
+		"RFC822Z":     time.RFC822Z,
+		"DateTime":    time.DateTime,
+		"DateOnly":    time.DateOnly,
+		"TimeOnly":    time.TimeOnly,
+		"Kitchen":     time.Kitchen,
+		layoutUnix:    layoutUnix,
+		layoutUnixMs:  layoutUnixMs,
+	}
+
+	// layouts accepted when no layout is declared, in addition to unix time and relative times; RFC3339 is
+	// tried first, and its error is reported if none matches
+	defaultLayouts = []string{time.RFC3339, time.DateTime, time.DateOnly}
+)
+
+// resolve a layout= tag option; named layouts are replaced by their value
+func resolveLayout(layout string) string {
+	if v, ok := namedLayouts[layout]; ok {
+		return v
+	}
+	return layout
+}
+
+// setter for time.Time fields; an empty layout accepts the default layouts
+func timeSetter(layout string, loc *time.Location) setter {
+	return func(field reflect.Value, fValue string) error {
+		v, err := parseTime(fValue, layout, loc)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(v))
+		return nil
+	}
+}
+
+// parseTime parses a time value, either absolute, using layout, or relative to the current time, e.g. "now-24h"
+// Values without time zone are parsed in loc.
+func parseTime(in string, layout string, loc *time.Location) (time.Time, error) {
+	if strings.HasPrefix(in, timeNow) {
+		return parseRelativeTime(in, loc)
+	}
+	switch layout {
+	case "":
+		if isInteger(in) {
+			return parseUnixTime(in, time.Second, loc)
+		}
+		var firstErr error
+		for _, l := range defaultLayouts {
+			v, err := time.ParseInLocation(l, in, loc)
+			if err == nil {
+				return v, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		return time.Time{}, firstErr
+	case layoutUnix:
+		return parseUnixTime(in, time.Second, loc)
+	case layoutUnixMs:
+		return parseUnixTime(in, time.Millisecond, loc)
+	}
+	return time.ParseInLocation(layout, in, loc)
+}
+
+// parse a unix timestamp, in multiples of unit since epoch
+func parseUnixTime(in string, unit time.Duration, loc *time.Location) (time.Time, error) {
+	v, err := strconv.ParseInt(in, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid unix time '%s', expected an integer number of %s since epoch", in, unitName(unit))
+	}
+	if unit == time.Millisecond {
+		return time.UnixMilli(v).In(loc), nil
+	}
+	return time.Unix(v, 0).In(loc), nil
+}
+
+// parse a time relative to the current time, such as "now", "now-24h" or "now+1w"
+func parseRelativeTime(in string, loc *time.Location) (time.Time, error) {
+	now := time.Now().In(loc)
+	offset := in[len(timeNow):]
+	if len(offset) == 0 {
+		return now, nil
+	}
+	if offset[0] != '-' && offset[0] != '+' {
+		return time.Time{}, fmt.Errorf("invalid relative time '%s', expected now, now-duration or now+duration, e.g. now-24h", in)
+	}
+	d, err := parseDuration(offset)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid relative time '%s', expected now, now-duration or now+duration, e.g. now-24h", in)
+	}
+	return now.Add(d), nil
+}
+
+// setter for time.Duration fields
+func durationSetter(field reflect.Value, fValue string) error {
+	v, err := parseDuration(fValue)
+	if err != nil {
+		return err
+	}
+	field.SetInt(int64(v))
+	return nil
+}
+
+// parseDuration parses a duration as time.ParseDuration, also accepting day and week units, e.g. "1w2d12h"
+func parseDuration(in string) (time.Duration, error) {
+	err := fmt.Errorf("invalid duration '%s', expected a sequence of numbers with unit ns, us, ms, s, m, h, d or w, e.g. 1h30m or 2d", in)
+	if !strings.ContainsAny(in, "dw") {
+		v, parseErr := time.ParseDuration(in)
+		if parseErr != nil {
+			return 0, err
+		}
+		return v, nil
+	}
+
+	// convert day and week components to hours
+	var result strings.Builder
+	s := in
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		result.WriteByte(s[0])
+		s = s[1:]
+	}
+	for len(s) > 0 {
+		i := 0
+		for i < len(s) && (s[i] == '.' || (s[i] >= '0' && s[i] <= '9')) {
+			i++
+		}
+		j := i
+		for j < len(s) && s[j] != '.' && (s[j] < '0' || s[j] > '9') {
+			j++
+		}
+		number, unit := s[:i], s[i:j]
+		s = s[j:]
+		var hours float64
+		switch unit {
+		case "d":
+			hours = day.Hours()
+		case "w":
+			hours = week.Hours()
+		default:
+			result.WriteString(number + unit)
+			continue
+		}
+		v, parseErr := strconv.ParseFloat(number, 64)
+		if parseErr != nil {
+			return 0, err
+		}
+		result.WriteString(strconv.FormatFloat(v*hours, 'f', -1, 64) + "h")
+	}
+	v, parseErr := time.ParseDuration(result.String())
+	if parseErr != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+// setter for *time.Location fields, from IANA time zone names
+func locationSetter(field reflect.Value, fValue string) error {
+	loc, err := time.LoadLocation(fValue)
+	if err != nil {
+		return fmt.Errorf("invalid time zone '%s', expected an IANA time zone name, e.g. Europe/Lisbon", fValue)
+	}
+	field.Set(reflect.ValueOf(loc))
+	return nil
+}
+
+// check if a string is an optionally signed integer
+func isInteger(in string) bool {
+	_, err := strconv.ParseInt(in, 10, 64)
+	return err == nil
+}
+
+// unit name, for error messages
+func unitName(unit time.Duration) string {
+	if unit == time.Millisecond {
+		return "milliseconds"
+	}
+	return "seconds"
+}
@@ -0,0 +1,138 @@
+package argv
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type TimeStruct struct {
+	Timeout  time.Duration   `argv:"timeout,optional"`
+	Retries  []time.Duration `argv:"retry,optional"`
+	Since    time.Time       `argv:"since,optional"`
+	Day      time.Time       `argv:"day,optional,layout=DateOnly"`
+	Local    time.Time       `argv:"local,optional,layout=DateTime,tz=Europe/Lisbon"`
+	Custom   *time.Time      `argv:"custom,optional,layout='02/01/2006 15h04'"`
+	Epoch    time.Time       `argv:"epoch,optional,layout=unix"`
+	EpochMs  time.Time       `argv:"epoch-ms,optional,layout=unixmilli"`
+	Location *time.Location  `argv:"location,optional"`
+}
+
+type TimeInvalidLayout struct {
+	Name string `argv:"name,layout=DateOnly"`
+}
+
+type TimeInvalidZone struct {
+	Since time.Time `argv:"since,tz=Mars/Olympus"`
+}
+
+func TestParseDuration(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expected time.Duration
+	}{
+		{"1h30m", 90 * time.Minute},
+		{"250ms", 250 * time.Millisecond},
+		{"2d", 48 * time.Hour},
+		{"1w2d12h", (7*24 + 2*24 + 12) * time.Hour},
+		{"1.5d", 36 * time.Hour},
+		{"-1d12h", -36 * time.Hour},
+		{"+1w", 7 * 24 * time.Hour},
+		{"0", 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			v, err := parseDuration(tc.in)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, v)
+		})
+	}
+	for _, in := range []string{"", "10", "d", "1dd", "1x", "1.2.3d", "bad"} {
+		_, err := parseDuration(in)
+		assert.EqualError(t, err, fmt.Sprintf("invalid duration '%s', expected a sequence of numbers with unit ns, us, ms, s, m, h, d or w, e.g. 1h30m or 2d", in))
+	}
+}
+
+func TestParseArgvTimeOptions(t *testing.T) {
+	lisbon, err := time.LoadLocation("Europe/Lisbon")
+	assert.Nil(t, err)
+
+	dest := &TimeStruct{}
+	err = ParseArgv(dest, []string{"--timeout", "1d", "--retry", "1s,1m", "--retry", "1h", "--since", "2024-03-01",
+		"--day", "2024-03-02", "--local", "2024-07-01 10:00:00", "--custom", "03/04/2024 12h30", "--epoch", "1700000000",
+		"--epoch-ms", "1700000000123", "--location", "Europe/Lisbon"})
+	assert.Nil(t, err)
+	assert.Equal(t, 24*time.Hour, dest.Timeout)
+	assert.Equal(t, []time.Duration{time.Second, time.Minute, time.Hour}, dest.Retries)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), dest.Since)
+	assert.Equal(t, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), dest.Day)
+	assert.True(t, time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC).Equal(dest.Local))
+	assert.Equal(t, lisbon, dest.Local.Location())
+	assert.Equal(t, time.Date(2024, 4, 3, 12, 30, 0, 0, time.UTC), *dest.Custom)
+	assert.Equal(t, int64(1700000000), dest.Epoch.Unix())
+	assert.Equal(t, int64(1700000000123), dest.EpochMs.UnixMilli())
+	assert.Equal(t, lisbon, dest.Location)
+
+	// default layouts include RFC3339, date and time, date only and unix time
+	for in, expected := range map[string]time.Time{
+		"2024-03-01T10:00:00+01:00": time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+		"2024-03-01 10:00:00":       time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		"86400":                     time.Date(1970, 1, 2, 0, 0, 0, 0, time.UTC),
+	} {
+		assert.Nil(t, ParseArgv(dest, []string{"--since", in}))
+		assert.True(t, expected.Equal(dest.Since), in)
+	}
+
+	// relative times
+	before := time.Now()
+	assert.Nil(t, ParseArgv(dest, []string{"--since", "now-24h", "--day", "now+1w", "--local", "now"}))
+	assert.WithinDuration(t, before.Add(-24*time.Hour), dest.Since, time.Minute)
+	assert.WithinDuration(t, before.Add(7*24*time.Hour), dest.Day, time.Minute)
+	assert.WithinDuration(t, before, dest.Local, time.Minute)
+	assert.Equal(t, lisbon, dest.Local.Location())
+}
+
+func TestParseArgvTimeErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "duration",
+			args:     []string{"--timeout", "soon"},
+			expected: "error parsing arg timeout: invalid duration 'soon', expected a sequence of numbers with unit ns, us, ms, s, m, h, d or w, e.g. 1h30m or 2d",
+		},
+		{
+			name:     "layout",
+			args:     []string{"--day", "01/02/2024"},
+			expected: `error parsing arg day: parsing time "01/02/2024" as "2006-01-02": cannot parse "01/02/2024" as "2006"`,
+		},
+		{
+			name:     "unix",
+			args:     []string{"--epoch", "2024-01-01"},
+			expected: "error parsing arg epoch: invalid unix time '2024-01-01', expected an integer number of seconds since epoch",
+		},
+		{
+			name:     "relative",
+			args:     []string{"--since", "now-yesterday"},
+			expected: "error parsing arg since: invalid relative time 'now-yesterday', expected now, now-duration or now+duration, e.g. now-24h",
+		},
+		{
+			name:     "location",
+			args:     []string{"--location", "Mars/Olympus"},
+			expected: "error parsing arg location: invalid time zone 'Mars/Olympus', expected an IANA time zone name, e.g. Europe/Lisbon",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.EqualError(t, ParseArgv(&TimeStruct{}, tc.args), tc.expected)
+		})
+	}
+
+	err := ParseArgv(&TimeInvalidLayout{}, []string{"--name", "x"})
+	assert.EqualError(t, err, "invalid argv tag on field TimeInvalidLayout.Name: layout requires a time.Time field")
+	err = ParseArgv(&TimeInvalidZone{}, []string{"--since", "now"})
+	assert.EqualError(t, err, "invalid argv tag on field TimeInvalidZone.Since: invalid time zone 'Mars/Olympus'")
+}
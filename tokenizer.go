@@ -0,0 +1,196 @@
+package argv
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	argTerminator = "--"
+)
+
+// a single named argument, as read from the argument list
+type token struct {
+	name  string
+	value string
+	raw   []string   // original arguments
+	spec  *FieldSpec // matching field, nil if unknown
+}
+
+// extractArgs tokenizes an argument list into named arguments and positional values
+//
+// Supported forms are "-name value", "--name value", "--name=value" and "-name=value"; short (single-character)
+// names are only recognized with a single dash ("-d 30"), and aliases resolve to the field primary name. For
+// backwards compatibility, names without a dash prefix are also accepted, but always require a value; if the
+// destination declares positional fields, unprefixed arguments are positional values instead. Boolean fields may
+// be used without value ("-verbose"), or with an explicit boolean literal ("-verbose false"); if the destination
+// declares positional fields, explicit values require the inline form ("-verbose=false"). Everything after a
+// "--" terminator is returned as positional values, and a lone "-" (usually meaning stdin) is a positional value if
+// the destination declares positional fields. Unknown names consume the next argument as value, unless it is
+// missing or is dash-prefixed.
+func extractArgs(args []string, s *Schema) ([]token, []string, error) {
+	tokens, positional, _, err := tokenize(args, s, false)
+	return tokens, positional, err
+}
+
+// tokenize implements extractArgs; if stopAtPositional is true, tokenizing stops at the first unprefixed argument,
+// and its index is returned, or -1 if none was found; unknown names then never consume an unprefixed value
+func tokenize(args []string, s *Schema, stopAtPositional bool) ([]token, []string, int, error) {
+	tokens := make([]token, 0)
+	positional := make([]string, 0)
+	i := 0
+	for i < len(args) {
+		arg := args[i]
+		i++
+		if arg == argTerminator {
+			positional = append(positional, args[i:]...)
+			break
+		}
+
+		name, value, dashes, hasValue := splitArg(arg)
+		prefixed := dashes > 0
+		if !prefixed && stopAtPositional {
+			return tokens, positional, i - 1, nil
+		}
+		if !prefixed && s.hasPositional() {
+			positional = append(positional, arg)
+			continue
+		}
+		if arg == "-" && s.hasPositional() {
+			positional = append(positional, arg)
+			continue
+		}
+		if len(name) == 0 {
+			return nil, nil, -1, ErrInvalidArgName
+		}
+		start := i - 1
+		spec := s.lookup(name, dashes)
+		if spec == nil && dashes == 1 && !isShortName(name) && s.lookup(string([]rune(name)[0]), 1) != nil {
+			clustered, next, err := expandCluster(args, i, name, value, hasValue, s)
+			if err == nil {
+				tokens = append(tokens, clustered...)
+				i = next
+				continue
+			}
+			// likely a misspelled long name, e.g. "-verbos"; handle it as unknown, so it gets suggestions
+			if len(suggest(name, s.names())) == 0 {
+				return nil, nil, -1, err
+			}
+		}
+		if spec != nil {
+			// use primary name for aliases
+			name = spec.Name
+		}
+		if !hasValue {
+			switch {
+			case prefixed && spec != nil && spec.Counter:
+				// counter occurrence, no value
+			case prefixed && spec != nil && spec.IsFlag():
+				// valueless flag; an explicit boolean literal may still follow, unless it may be a positional value
+				value = "true"
+				if !s.hasPositional() && i < len(args) && isBoolLiteral(args[i]) {
+					value = args[i]
+					i++
+				}
+			case prefixed && spec == nil:
+				// when stopping at positional values, the next argument may be a command name
+				if !stopAtPositional && i < len(args) && !strings.HasPrefix(args[i], "-") {
+					value = args[i]
+					i++
+				}
+			default:
+				if i >= len(args) {
+					if prefixed {
+						return nil, nil, -1, ErrMissingValue(name)
+					}
+					// legacy form, odd argument count
+					return nil, nil, -1, ErrInvalidParameterCount
+				}
+				value = args[i]
+				i++
+			}
+		}
+		tokens = append(tokens, token{name: name, value: value, raw: args[start:i], spec: spec})
+	}
+	return tokens, positional, -1, nil
+}
+
+// expandCluster expands a cluster of short names, such as "-xzf archive.tgz" or "-vvv"
+// Boolean and counter fields take no value; the first short name requiring a value consumes the remaining
+// characters ("-ofile"), or the next argument. An inline value ("-xv=false") is assigned to the last name.
+// args[i] is the argument after the cluster; returns the expanded tokens and the index of the next argument.
+func expandCluster(args []string, i int, cluster string, value string, hasValue bool, s *Schema) ([]token, int, error) {
+	start := i - 1
+	runes := []rune(cluster)
+	result := make([]token, 0, len(runes))
+	for j := 0; j < len(runes); j++ {
+		name := string(runes[j])
+		spec := s.lookup(name, 1)
+		if spec == nil {
+			return nil, i, ErrInvalidCluster(name, args[start])
+		}
+		if spec.Counter {
+			result = append(result, token{name: spec.Name, value: "", spec: spec})
+			continue
+		}
+		if spec.IsFlag() {
+			result = append(result, token{name: spec.Name, value: "true", spec: spec})
+			continue
+		}
+
+		// name requires a value
+		var v string
+		switch {
+		case j+1 < len(runes):
+			v = string(runes[j+1:])
+			if hasValue {
+				v += "=" + value
+			}
+		case hasValue:
+			v = value
+		case i < len(args):
+			v = args[i]
+			i++
+		default:
+			return nil, i, ErrMissingValue(spec.Name)
+		}
+		result = append(result, token{name: spec.Name, value: v, spec: spec})
+		hasValue = false
+		break
+	}
+	if hasValue {
+		result[len(result)-1].value = value
+	}
+	for j := range result {
+		result[j].raw = args[start:i]
+	}
+	return result, i, nil
+}
+
+// splitArg removes the dash prefix from an argument and splits an inline "=value", if present
+// returns name, value, dash count and a flag signaling if the argument has an inline value
+func splitArg(arg string) (string, string, int, bool) {
+	var name string
+	var dashes int
+	switch {
+	case strings.HasPrefix(arg, "--"):
+		name = arg[2:]
+		dashes = 2
+	case strings.HasPrefix(arg, "-"):
+		name = arg[1:]
+		dashes = 1
+	default:
+		// legacy form, name without prefix; inline values are not supported
+		return arg, "", 0, false
+	}
+	if idx := strings.IndexByte(name, '='); idx > -1 {
+		return name[:idx], name[idx+1:], dashes, true
+	}
+	return name, "", dashes, false
+}
+
+// check if a string is a valid boolean value
+func isBoolLiteral(in string) bool {
+	_, err := strconv.ParseBool(in)
+	return err == nil
+}
@@ -0,0 +1,255 @@
+package argv
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type ArgStructFlags struct {
+	Name    string `argv:"name"`
+	Verbose bool   `argv:"verbose,optional"`
+	Debug   bool   `argv:"debug,optional"`
+	Count   int    `argv:"count,optional"`
+}
+
+func TestExtractArgs(t *testing.T) {
+	s, err := ParseSchema(&ArgStructFlags{})
+	assert.Nil(t, err)
+	testCases := []struct {
+		name               string
+		args               []string
+		expected           error
+		expectedTokens     []token
+		expectedPositional []string
+	}{
+		{
+			name: "legacy pairs",
+			args: []string{"name", "value", "count", "3"},
+			expectedTokens: []token{
+				{"name", "value", []string{"name", "value"}, nil},
+				{"count", "3", []string{"count", "3"}, nil},
+			},
+			expectedPositional: []string{},
+		},
+		{
+			name: "dash prefixes",
+			args: []string{"-name", "value", "--count", "-3"},
+			expectedTokens: []token{
+				{"name", "value", []string{"-name", "value"}, nil},
+				{"count", "-3", []string{"--count", "-3"}, nil},
+			},
+			expectedPositional: []string{},
+		},
+		{
+			name: "inline values",
+			args: []string{"--name=a=b", "-count=", "--verbose=false"},
+			expectedTokens: []token{
+				{"name", "a=b", []string{"--name=a=b"}, nil},
+				{"count", "", []string{"-count="}, nil},
+				{"verbose", "false", []string{"--verbose=false"}, nil},
+			},
+			expectedPositional: []string{},
+		},
+		{
+			name: "valueless flags",
+			args: []string{"-verbose", "--debug", "-name", "x"},
+			expectedTokens: []token{
+				{"verbose", "true", []string{"-verbose"}, nil},
+				{"debug", "true", []string{"--debug"}, nil},
+				{"name", "x", []string{"-name", "x"}, nil},
+			},
+			expectedPositional: []string{},
+		},
+		{
+			name: "flag with explicit value",
+			args: []string{"-verbose", "0", "--debug", "true"},
+			expectedTokens: []token{
+				{"verbose", "0", []string{"-verbose", "0"}, nil},
+				{"debug", "true", []string{"--debug", "true"}, nil},
+			},
+			expectedPositional: []string{},
+		},
+		{
+			name:               "terminator",
+			args:               []string{"-name", "x", "--", "-verbose", "--", "y"},
+			expectedTokens:     []token{{"name", "x", []string{"-name", "x"}, nil}},
+			expectedPositional: []string{"-verbose", "--", "y"},
+		},
+		{
+			name: "unknown names",
+			args: []string{"-other", "x", "--flag", "--last"},
+			expectedTokens: []token{
+				{"other", "x", []string{"-other", "x"}, nil},
+				{"flag", "", []string{"--flag"}, nil},
+				{"last", "", []string{"--last"}, nil},
+			},
+			expectedPositional: []string{},
+		},
+		{
+			name:     "missing value",
+			args:     []string{"-name", "x", "--count"},
+			expected: ErrMissingValue("count"),
+		},
+		{
+			name:     "missing value, legacy form",
+			args:     []string{"name", "x", "count"},
+			expected: ErrInvalidParameterCount,
+		},
+		{
+			name:     "lone dash",
+			args:     []string{"-"},
+			expected: ErrInvalidArgName,
+		},
+		{
+			name:     "empty name",
+			args:     []string{"--=x"},
+			expected: ErrInvalidArgName,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens, positional, err := extractArgs(tc.args, s)
+			if tc.expected != nil {
+				assert.Equal(t, tc.expected, err)
+				return
+			}
+			assert.Nil(t, err)
+			for i := range tokens {
+				tokens[i].spec = nil
+			}
+			assert.Equal(t, tc.expectedTokens, tokens)
+			assert.Equal(t, tc.expectedPositional, positional)
+		})
+	}
+}
+
+func TestParseArgvFlags(t *testing.T) {
+	testCases := []struct {
+		name           string
+		args           []string
+		expected       error
+		expectedValues ArgStructFlags
+	}{
+		{
+			name:           "valueless flags",
+			args:           []string{"--name=x", "-verbose", "--count", "2"},
+			expectedValues: ArgStructFlags{Name: "x", Verbose: true, Count: 2},
+		},
+		{
+			name:           "trailing flag",
+			args:           []string{"--name", "x", "--debug"},
+			expectedValues: ArgStructFlags{Name: "x", Debug: true},
+		},
+		{
+			name:           "explicit false",
+			args:           []string{"-verbose=false", "-debug", "false", "-name", "x"},
+			expectedValues: ArgStructFlags{Name: "x"},
+		},
+		{
+			name:     "unexpected positional",
+			args:     []string{"-name", "x", "--", "extra"},
+			expected: ErrUnexpectedArg("extra"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dest := &ArgStructFlags{}
+			err := ParseArgv(dest, tc.args)
+			if tc.expected != nil {
+				assert.Equal(t, tc.expected, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, &tc.expectedValues, dest)
+		})
+	}
+}
+
+type ArgStructCluster struct {
+	Extract bool   `argv:"extract|x,optional"`
+	Gzip    bool   `argv:"gzip|z,optional"`
+	File    string `argv:"file|f,optional"`
+	Verbose int    `argv:"verbose|v,optional,counter"`
+}
+
+type ArgStructInvalidCounter struct {
+	Verbose string `argv:"verbose|v,counter"`
+}
+
+func TestParseArgvClusters(t *testing.T) {
+	testCases := []struct {
+		name           string
+		args           []string
+		expected       string
+		expectedValues ArgStructCluster
+	}{
+		{
+			name:           "flags and value",
+			args:           []string{"-xzf", "archive.tgz"},
+			expectedValues: ArgStructCluster{Extract: true, Gzip: true, File: "archive.tgz"},
+		},
+		{
+			name:           "attached value",
+			args:           []string{"-xfarchive.tgz", "-z"},
+			expectedValues: ArgStructCluster{Extract: true, Gzip: true, File: "archive.tgz"},
+		},
+		{
+			name:           "inline value",
+			args:           []string{"-zf=archive.tgz"},
+			expectedValues: ArgStructCluster{Gzip: true, File: "archive.tgz"},
+		},
+		{
+			name:           "inline flag value",
+			args:           []string{"-xz=false"},
+			expectedValues: ArgStructCluster{Extract: true},
+		},
+		{
+			name:           "counter",
+			args:           []string{"-vvv"},
+			expectedValues: ArgStructCluster{Verbose: 3},
+		},
+		{
+			name:           "counter, mixed forms",
+			args:           []string{"-vxv", "--verbose", "-v"},
+			expectedValues: ArgStructCluster{Extract: true, Verbose: 4},
+		},
+		{
+			name:           "counter, explicit value",
+			args:           []string{"--verbose=5", "-v"},
+			expectedValues: ArgStructCluster{Verbose: 6},
+		},
+		{
+			name:     "unknown flag",
+			args:     []string{"-xqf", "archive.tgz"},
+			expected: "unknown flag '-q' in '-xqf'",
+		},
+		{
+			name:     "missing value",
+			args:     []string{"-xzf"},
+			expected: "value for arg 'file' is missing",
+		},
+		{
+			name:     "long name with double dash",
+			args:     []string{"--xz"},
+			expected: "unknown arg 'xz'",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dest := &ArgStructCluster{}
+			err := ParseArgv(dest, tc.args)
+			if len(tc.expected) > 0 {
+				assert.EqualError(t, err, tc.expected)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, &tc.expectedValues, dest)
+		})
+	}
+
+	err := ParseArgv(&ArgStructInvalidCounter{}, []string{"-v"})
+	assert.EqualError(t, err, "invalid argv tag on field ArgStructInvalidCounter.Verbose: counter requires an integer field")
+}
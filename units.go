@@ -0,0 +1,143 @@
+package argv
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+const (
+	UnitBytes = "bytes" // byte sizes, such as 512MiB or 2G, for integer fields
+	UnitSI    = "si"    // SI prefixes, such as 1.5k or 250m, for integer and float fields
+)
+
+var (
+	// byte size units, matched case-insensitively; decimal units are powers of 1000, binary units powers of 1024
+	byteUnits = map[string]*big.Rat{
+		"": pow(10, 0), "b": pow(10, 0),
+		"k": pow(10, 3), "kb": pow(10, 3), "ki": pow(2, 10), "kib": pow(2, 10),
+		"m": pow(10, 6), "mb": pow(10, 6), "mi": pow(2, 20), "mib": pow(2, 20),
+		"g": pow(10, 9), "gb": pow(10, 9), "gi": pow(2, 30), "gib": pow(2, 30),
+		"t": pow(10, 12), "tb": pow(10, 12), "ti": pow(2, 40), "tib": pow(2, 40),
+		"p": pow(10, 15), "pb": pow(10, 15), "pi": pow(2, 50), "pib": pow(2, 50),
+		"e": pow(10, 18), "eb": pow(10, 18), "ei": pow(2, 60), "eib": pow(2, 60),
+	}
+
+	// SI prefixes, matched case-sensitively, as m is milli and M is mega
+	siUnits = map[string]*big.Rat{
+		"":  pow(10, 0),
+		"k": pow(10, 3), "K": pow(10, 3), "M": pow(10, 6), "G": pow(10, 9), "T": pow(10, 12), "P": pow(10, 15),
+		"E": pow(10, 18),
+		"m": pow(10, -3), "u": pow(10, -6), "µ": pow(10, -6), "n": pow(10, -9), "p": pow(10, -12),
+	}
+)
+
+// base raised to exp, as an exact rational number
+func pow(base int64, exp int64) *big.Rat {
+	if exp < 0 {
+		return new(big.Rat).Inv(pow(base, -exp))
+	}
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(base), big.NewInt(exp), nil))
+}
+
+// validate a unit= tag option against the field type
+func checkUnit(unit string, t reflect.Type) error {
+	kind := t.Kind()
+	isInt := isIntKind(kind)
+	switch unit {
+	case UnitBytes:
+		if !isInt || t == durationType {
+			return fmt.Errorf("unit %s requires an integer field", unit)
+		}
+	case UnitSI:
+		if (!isInt && kind != reflect.Float32 && kind != reflect.Float64) || t == durationType {
+			return fmt.Errorf("unit %s requires an integer or float field", unit)
+		}
+	default:
+		return fmt.Errorf("invalid unit '%s'", unit)
+	}
+	return nil
+}
+
+// setter for integer fields with a unit, e.g. "512MiB"; plain integers are accepted as well
+func scaledIntSetter(unit string, size int) setter {
+	return func(field reflect.Value, fValue string) error {
+		v, err := parseScaled(fValue, unit)
+		if err != nil {
+			return err
+		}
+		if !v.IsInt() {
+			return fmt.Errorf("invalid value '%s', expected an integer", fValue)
+		}
+		n := v.Num()
+		if isUintKind(field.Kind()) {
+			if n.Sign() < 0 || n.BitLen() > size {
+				return fmt.Errorf("value '%s' out of range", fValue)
+			}
+			field.SetUint(n.Uint64())
+			return nil
+		}
+		if !n.IsInt64() || field.OverflowInt(n.Int64()) {
+			return fmt.Errorf("value '%s' out of range", fValue)
+		}
+		field.SetInt(n.Int64())
+		return nil
+	}
+}
+
+// setter for float fields with a unit, e.g. "1.5k"; plain floats, such as "1e3", are accepted as well
+func scaledFloatSetter(unit string, size int) setter {
+	return func(field reflect.Value, fValue string) error {
+		if v, err := parseFloat(fValue, size); err == nil {
+			field.SetFloat(v)
+			return nil
+		}
+		v, err := parseScaled(fValue, unit)
+		if err != nil {
+			return err
+		}
+		f, _ := v.Float64()
+		if field.OverflowFloat(f) {
+			return fmt.Errorf("value '%s' out of range", fValue)
+		}
+		field.SetFloat(f)
+		return nil
+	}
+}
+
+// parseScaled parses a decimal number followed by an optional unit, as an exact rational number
+// Integers with a base prefix, such as 0x1F, are accepted without unit.
+func parseScaled(in string, unit string) (*big.Rat, error) {
+	if v, err := parseInt(in, 64); err == nil {
+		return new(big.Rat).SetInt64(v), nil
+	}
+	if v, err := parseUint(in, 64); err == nil {
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(v)), nil
+	}
+
+	i := 0
+	for i < len(in) && (in[i] == '.' || in[i] == '_' || in[i] == '+' || in[i] == '-' || (in[i] >= '0' && in[i] <= '9')) {
+		i++
+	}
+	number, suffix := in[:i], strings.TrimSpace(in[i:])
+	units := siUnits
+	if unit == UnitBytes {
+		units = byteUnits
+		suffix = strings.ToLower(suffix)
+	}
+	factor, ok := units[suffix]
+	literal, _ := intLiteral(number)
+	v, valid := new(big.Rat).SetString(literal)
+	if !ok || !valid || len(number) == 0 {
+		if unit == UnitBytes {
+			return nil, fmt.Errorf("invalid byte size '%s', expected a number with unit B, kB, MB, GB, TB, PB, EB, KiB, MiB, GiB, TiB, PiB or EiB, e.g. 512MiB", in)
+		}
+		return nil, fmt.Errorf("invalid value '%s', expected a number with SI prefix k, M, G, T, P, E, m, u, n or p, e.g. 1.5k", in)
+	}
+	return v.Mul(v, factor), nil
+}
+
+func isUintKind(kind reflect.Kind) bool {
+	return kind >= reflect.Uint && kind <= reflect.Uintptr
+}
@@ -0,0 +1,136 @@
+package argv
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type UnitStruct struct {
+	Memory  uint64    `argv:"memory,optional,unit=bytes"`
+	Buffer  int32     `argv:"buffer,optional,unit=bytes"`
+	Chunks  []uint    `argv:"chunk,optional,unit=bytes"`
+	Limit   *int64    `argv:"limit,optional,unit=bytes"`
+	Rate    float64   `argv:"rate,optional,unit=si"`
+	Voltage float32   `argv:"voltage,optional,unit=si"`
+	Count   int       `argv:"count,optional,unit=si"`
+	Levels  []float64 `argv:"level,optional,unit=si"`
+}
+
+type UnitInvalidType struct {
+	Name string `argv:"name,unit=bytes"`
+}
+
+type UnitInvalidFloat struct {
+	Ratio float64 `argv:"ratio,unit=bytes"`
+}
+
+type UnitInvalidName struct {
+	Size int `argv:"size,unit=furlongs"`
+}
+
+func TestParseArgvUnits(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected UnitStruct
+	}{
+		{
+			name:     "binary bytes",
+			args:     []string{"--memory", "512MiB", "--buffer", "64k", "--chunk", "1KiB,2ki, 4 KiB"},
+			expected: UnitStruct{Memory: 512 << 20, Buffer: 64000, Chunks: []uint{1024, 2048, 4096}},
+		},
+		{
+			name:     "decimal bytes",
+			args:     []string{"--memory", "2G", "--buffer", "1.5kB", "--chunk", "10b"},
+			expected: UnitStruct{Memory: 2000000000, Buffer: 1500, Chunks: []uint{10}},
+		},
+		{
+			name:     "plain values",
+			args:     []string{"--memory", "0x1000", "--buffer", "1_024", "--rate", "1e3", "--count", "-12"},
+			expected: UnitStruct{Memory: 4096, Buffer: 1024, Rate: 1000, Count: -12},
+		},
+		{
+			name:     "si prefixes",
+			args:     []string{"--rate", "1.5k", "--voltage", "250m", "--count", "3M", "--level", "10u,2n,-1.5G"},
+			expected: UnitStruct{Rate: 1500, Voltage: 0.25, Count: 3000000, Levels: []float64{10e-6, 2e-9, -1.5e9}},
+		},
+		{
+			name:     "largest value",
+			args:     []string{"--memory", "15EiB"},
+			expected: UnitStruct{Memory: 15 << 60},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dest := &UnitStruct{}
+			assert.Nil(t, ParseArgv(dest, tc.args))
+			assert.Equal(t, &tc.expected, dest)
+		})
+	}
+
+	dest := &UnitStruct{}
+	assert.Nil(t, ParseArgv(dest, []string{"--limit", "1TiB"}))
+	assert.Equal(t, int64(1<<40), *dest.Limit)
+
+	s, err := ParseSchema(&UnitStruct{})
+	assert.Nil(t, err)
+	assert.Equal(t, UnitBytes, s.Lookup("memory").Unit)
+	assert.Equal(t, UnitSI, s.Lookup("rate").Unit)
+}
+
+func TestParseArgvUnitErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "unknown byte unit",
+			args:     []string{"--memory", "2GiG"},
+			expected: "error parsing arg memory: invalid byte size '2GiG', expected a number with unit B, kB, MB, GB, TB, PB, EB, KiB, MiB, GiB, TiB, PiB or EiB, e.g. 512MiB",
+		},
+		{
+			name:     "missing number",
+			args:     []string{"--memory", "MiB"},
+			expected: "error parsing arg memory: invalid byte size 'MiB', expected a number with unit B, kB, MB, GB, TB, PB, EB, KiB, MiB, GiB, TiB, PiB or EiB, e.g. 512MiB",
+		},
+		{
+			name:     "fractional bytes",
+			args:     []string{"--memory", "1.3B"},
+			expected: "error parsing arg memory: invalid value '1.3B', expected an integer",
+		},
+		{
+			name:     "overflow",
+			args:     []string{"--buffer", "2GiB"},
+			expected: "error parsing arg buffer: value '2GiB' out of range",
+		},
+		{
+			name:     "negative size",
+			args:     []string{"--memory", "-1KiB"},
+			expected: "error parsing arg memory: value '-1KiB' out of range",
+		},
+		{
+			// SI prefixes are case-sensitive
+			name:     "unknown si prefix",
+			args:     []string{"--rate", "1.5g"},
+			expected: "error parsing arg rate: invalid value '1.5g', expected a number with SI prefix k, M, G, T, P, E, m, u, n or p, e.g. 1.5k",
+		},
+		{
+			name:     "fractional integer",
+			args:     []string{"--count", "1500m"},
+			expected: "error parsing arg count: invalid value '1500m', expected an integer",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.EqualError(t, ParseArgv(&UnitStruct{}, tc.args), tc.expected)
+		})
+	}
+
+	err := ParseArgv(&UnitInvalidType{}, []string{"--name", "x"})
+	assert.EqualError(t, err, "invalid argv tag on field UnitInvalidType.Name: unit bytes requires an integer field")
+	err = ParseArgv(&UnitInvalidFloat{}, []string{"--ratio", "1"})
+	assert.EqualError(t, err, "invalid argv tag on field UnitInvalidFloat.Ratio: unit bytes requires an integer field")
+	err = ParseArgv(&UnitInvalidName{}, []string{"--size", "1"})
+	assert.EqualError(t, err, "invalid argv tag on field UnitInvalidName.Size: invalid unit 'furlongs'")
+}
@@ -0,0 +1,267 @@
+package argv
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// validation tag options, in evaluation order
+var ruleNames = []string{"nonempty", "len", "min", "max", "pattern"}
+
+// validation rule, declared as a tag option
+type rule struct {
+	name  string // option name, e.g. "min"
+	value string // option value, as declared; empty for nonempty
+	check func(v reflect.Value) error
+}
+
+// rule as declared, e.g. "min=2048"
+func (r rule) String() string {
+	if r.name == "nonempty" {
+		return r.name
+	}
+	return r.name + "=" + r.value
+}
+
+// fieldRules compiles the validation rules declared in a field tag
+// Numbers and times are compared by value; strings, slices and maps by length, unless their items are numbers or
+// times, which are compared item by item. Patterns match the whole string, or each string item.
+func (p *Parser) fieldRules(tag tagSpec, spec *FieldSpec, opts valueOptions) ([]rule, error) {
+	t := spec.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	item := t
+	if spec.multi {
+		item = t.Elem()
+	}
+	var result []rule
+	var lower, upper reflect.Value
+	for _, name := range ruleNames {
+		value, ok := tag.get(name)
+		if !ok {
+			continue
+		}
+		r := rule{name: name, value: value}
+		switch {
+		case name == "nonempty" || name == "len":
+			if !hasLength(t) {
+				return nil, fmt.Errorf("%s requires a string, slice or map field", name)
+			}
+			n, err := parseLength(name, value)
+			if err != nil {
+				return nil, err
+			}
+			r.check = lengthCheck(name, n)
+
+		case name == "pattern":
+			if item.Kind() != reflect.String {
+				return nil, fmt.Errorf("pattern requires a string field")
+			}
+			if _, err := regexp.Compile(value); err != nil {
+				return nil, fmt.Errorf("invalid pattern '%s': %w", value, err)
+			}
+			// patterns match the whole value
+			re := regexp.MustCompile("^(?:" + value + ")$")
+			r.check = eachItem(spec.multi, func(v reflect.Value) error {
+				if !re.MatchString(v.String()) {
+					return fmt.Errorf("value '%s' does not match pattern=%s", v.String(), value)
+				}
+				return nil
+			})
+
+		case isOrdered(item):
+			bound := reflect.New(item).Elem()
+			if err := p.newSetter(item, opts)(bound, value); err != nil {
+				return nil, fmt.Errorf("invalid %s '%s': %w", name, value, err)
+			}
+			if name == "min" {
+				lower = bound
+			} else {
+				upper = bound
+			}
+			r.check = eachItem(spec.multi, boundCheck(name, value, bound))
+
+		case hasLength(t):
+			n, err := parseLength(name, value)
+			if err != nil {
+				return nil, err
+			}
+			if name == "min" {
+				lower = reflect.ValueOf(n)
+			} else {
+				upper = reflect.ValueOf(n)
+			}
+			r.check = lengthCheck(name, n)
+
+		default:
+			return nil, fmt.Errorf("%s requires a numeric, time, string, slice or map field", name)
+		}
+		result = append(result, r)
+	}
+	if lower.IsValid() && upper.IsValid() && compareValues(lower, upper) > 0 {
+		return nil, fmt.Errorf("min is greater than max")
+	}
+	return result, nil
+}
+
+// validate checks the value of a field against its rules
+func (f *FieldSpec) validate(field reflect.Value) error {
+	r, err := f.checkRules(field)
+	if err == nil {
+		return nil
+	}
+	result := ErrValidation(f.Name, r.String(), err)
+	if keyErr, ok := err.(*keyError); ok {
+		result.Key = keyErr.key
+		result.FieldError = keyErr.err
+	}
+	return result
+}
+
+// checkRules checks the value of a field against its rules, returning the first failed rule and its error
+func (f *FieldSpec) checkRules(field reflect.Value) (rule, error) {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return rule{}, nil
+		}
+		field = field.Elem()
+	}
+	for _, r := range f.rules {
+		if err := r.check(field); err != nil {
+			return r, err
+		}
+	}
+	return rule{}, nil
+}
+
+// checkDefault converts the default value and checks it against the field rules, so invalid defaults are reported
+// when the schema is compiled
+func (f *FieldSpec) checkDefault() error {
+	value := reflect.New(f.Type).Elem()
+	if err := f.assign(value, f.Default); err != nil {
+		return err
+	}
+	_, err := f.checkRules(value)
+	return err
+}
+
+// apply a check to a value, or to each item of a slice or map value; map errors name the offending key
+func eachItem(multi bool, check func(v reflect.Value) error) func(v reflect.Value) error {
+	if !multi {
+		return check
+	}
+	return func(v reflect.Value) error {
+		if v.Kind() == reflect.Map {
+			iter := v.MapRange()
+			for iter.Next() {
+				if err := check(iter.Value()); err != nil {
+					return &keyError{key: fmt.Sprint(iter.Key().Interface()), err: err}
+				}
+			}
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := check(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// check a number or time against a min or max bound
+func boundCheck(name string, value string, bound reflect.Value) func(v reflect.Value) error {
+	return func(v reflect.Value) error {
+		c := compareValues(v, bound)
+		switch {
+		case name == "min" && c < 0:
+			return fmt.Errorf("value %v is less than min=%s", v.Interface(), value)
+		case name == "max" && c > 0:
+			return fmt.Errorf("value %v is greater than max=%s", v.Interface(), value)
+		}
+		return nil
+	}
+}
+
+// check the length of a string, slice or map; strings are measured in characters
+func lengthCheck(name string, n int) func(v reflect.Value) error {
+	return func(v reflect.Value) error {
+		length := v.Len()
+		if v.Kind() == reflect.String {
+			length = utf8.RuneCountInString(v.String())
+		}
+		switch {
+		case name == "nonempty" && length == 0:
+			return fmt.Errorf("value is empty, expected nonempty")
+		case name == "len" && length != n:
+			return fmt.Errorf("length %d does not match len=%d", length, n)
+		case name == "min" && length < n:
+			return fmt.Errorf("length %d is less than min=%d", length, n)
+		case name == "max" && length > n:
+			return fmt.Errorf("length %d is greater than max=%d", length, n)
+		}
+		return nil
+	}
+}
+
+// parse a length bound; nonempty takes no value
+func parseLength(name string, value string) (int, error) {
+	if name == "nonempty" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s '%s', expected a length", name, value)
+	}
+	return n, nil
+}
+
+// check if values of type t are compared by value
+func isOrdered(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return t == timeType
+}
+
+// check if values of type t are compared by length
+func hasLength(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// compare two values of the same ordered type, returning -1, 0 or 1
+func compareValues(a reflect.Value, b reflect.Value) int {
+	switch {
+	case a.CanInt():
+		return compare(a.Int(), b.Int())
+	case a.CanUint():
+		return compare(a.Uint(), b.Uint())
+	case a.CanFloat():
+		return compare(a.Float(), b.Float())
+	case a.Type() == timeType:
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time))
+	}
+	return 0
+}
+
+func compare[T int64 | uint64 | float64](a T, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
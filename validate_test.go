@@ -0,0 +1,197 @@
+package argv
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type ValidateStruct struct {
+	Bits    uint              `argv:"bits,default=2048,min=2048,max=8192"`
+	Ratio   *float64          `argv:"ratio,optional,min=0,max=1"`
+	Timeout time.Duration     `argv:"timeout,optional,min=1s,max=1h"`
+	Memory  uint64            `argv:"memory,optional,unit=bytes,max=1GiB"`
+	Since   time.Time         `argv:"since,optional,layout=DateOnly,min=2000-01-01"`
+	Name    string            `argv:"name,optional,nonempty,max=8,pattern='[a-z][a-z0-9-]*'"`
+	Code    string            `argv:"code,optional,len=3"`
+	Serial  string            `argv:"serial,optional,pattern='\\d{2,4}'"`
+	Hosts   []string          `argv:"host,optional,nonempty,max=2,pattern=[a-z.]+"`
+	Ports   []int             `argv:"port,optional,min=1,max=65535"`
+	Limits  map[string]int    `argv:"limit,optional,min=0"`
+	Labels  map[string]string `argv:"label,optional,min=1"`
+	Files   []string          `argv:"#rest,optional,max=2"`
+}
+
+type ValidateInvalidLength struct {
+	Count int `argv:"count,len=3"`
+}
+
+type ValidateInvalidPattern struct {
+	Name string `argv:"name,pattern=[a-z"`
+}
+
+type ValidateInvalidBound struct {
+	Count int `argv:"count,min=ten"`
+}
+
+type ValidateInvalidRange struct {
+	Name string `argv:"name,min=8,max=4"`
+}
+
+type ValidateInvalidDefault struct {
+	Bits uint `argv:"bits,default=1024,min=2048"`
+}
+
+type ValidateInvalidType struct {
+	Enabled bool `argv:"enabled,min=1"`
+}
+
+func TestParseArgvValidation(t *testing.T) {
+	dest := &ValidateStruct{}
+	err := ParseArgv(dest, []string{"--ratio", "0.5", "--timeout", "30s", "--memory", "512MiB", "--since", "2024-03-01",
+		"--name", "web-1", "--code", "PRT", "--serial", "1234", "--host", "a.example,b.example", "--port", "80,443", "--limit", "cpu=0",
+		"--label", "env=prod", "--", "a.txt", "b.txt"})
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2048), dest.Bits)
+	assert.Equal(t, 0.5, *dest.Ratio)
+	assert.Equal(t, "web-1", dest.Name)
+	assert.Equal(t, []string{"a.txt", "b.txt"}, dest.Files)
+
+	// rules are only checked on assigned fields
+	assert.Nil(t, ParseArgv(&ValidateStruct{}, []string{"--bits", "4096"}))
+
+	testCases := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		expected error
+	}{
+		{
+			name:     "min",
+			args:     []string{"--bits", "1024"},
+			expected: ErrValidation("bits", "min=2048", fmt.Errorf("value 1024 is less than min=2048")),
+		},
+		{
+			name:     "max",
+			args:     []string{"--bits", "16384"},
+			expected: ErrValidation("bits", "max=8192", fmt.Errorf("value 16384 is greater than max=8192")),
+		},
+		{
+			name:     "pointer",
+			args:     []string{"--ratio", "1.5"},
+			expected: ErrValidation("ratio", "max=1", fmt.Errorf("value 1.5 is greater than max=1")),
+		},
+		{
+			name:     "duration",
+			args:     []string{"--timeout", "2h"},
+			expected: ErrValidation("timeout", "max=1h", fmt.Errorf("value 2h0m0s is greater than max=1h")),
+		},
+		{
+			name:     "unit",
+			args:     []string{"--memory", "2GiB"},
+			expected: ErrValidation("memory", "max=1GiB", fmt.Errorf("value 2147483648 is greater than max=1GiB")),
+		},
+		{
+			name:     "time",
+			args:     []string{"--since", "1999-12-31"},
+			expected: ErrValidation("since", "min=2000-01-01", fmt.Errorf("value 1999-12-31 00:00:00 +0000 UTC is less than min=2000-01-01")),
+		},
+		{
+			name:     "nonempty",
+			args:     []string{"--name", ""},
+			expected: ErrValidation("name", "nonempty", fmt.Errorf("value is empty, expected nonempty")),
+		},
+		{
+			name:     "max length",
+			args:     []string{"--name", "webserver"},
+			expected: ErrValidation("name", "max=8", fmt.Errorf("length 9 is greater than max=8")),
+		},
+		{
+			name:     "pattern",
+			args:     []string{"--name", "Web"},
+			expected: ErrValidation("name", "pattern=[a-z][a-z0-9-]*", fmt.Errorf("value 'Web' does not match pattern=[a-z][a-z0-9-]*")),
+		},
+		{
+			// backslashes are kept inside quotes
+			name:     "pattern escapes",
+			args:     []string{"--serial", "d12"},
+			expected: ErrValidation("serial", `pattern=\d{2,4}`, fmt.Errorf(`value 'd12' does not match pattern=\d{2,4}`)),
+		},
+		{
+			// lengths are measured in characters
+			name:     "len",
+			args:     []string{"--code", "€€€€"},
+			expected: ErrValidation("code", "len=3", fmt.Errorf("length 4 does not match len=3")),
+		},
+		{
+			name:     "slice length",
+			args:     []string{"--host", "a,b,c"},
+			expected: ErrValidation("host", "max=2", fmt.Errorf("length 3 is greater than max=2")),
+		},
+		{
+			name:     "slice pattern",
+			args:     []string{"--host", "a.example,b_example"},
+			expected: ErrValidation("host", "pattern=[a-z.]+", fmt.Errorf("value 'b_example' does not match pattern=[a-z.]+")),
+		},
+		{
+			name:     "slice items",
+			args:     []string{"--port", "80,0"},
+			expected: ErrValidation("port", "min=1", fmt.Errorf("value 0 is less than min=1")),
+		},
+		{
+			name:     "map length",
+			args:     []string{"--label", ""},
+			expected: ErrValidation("label", "min=1", fmt.Errorf("length 0 is less than min=1")),
+		},
+		{
+			name:     "rest",
+			args:     []string{"a", "b", "c"},
+			expected: ErrValidation("#rest", "max=2", fmt.Errorf("length 3 is greater than max=2")),
+		},
+		{
+			name:     "env",
+			env:      map[string]string{"CERT_BITS": "512"},
+			expected: ErrValidation("bits", "min=2048", fmt.Errorf("value 512 is less than min=2048")),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ParseArgv(&ValidateStruct{}, append([]string{"--code", "abc"}, tc.args...),
+				WithEnvPrefix("CERT_"), WithEnvLookup(envMap(tc.env)))
+			assert.Equal(t, tc.expected, err)
+		})
+	}
+
+	err = ParseArgv(&ValidateStruct{}, []string{"--limit", "cpu=2,mem=-1"})
+	assert.EqualError(t, err, "invalid value for arg limit, key 'mem': value -1 is less than min=0")
+	assert.Equal(t, ErrTypeValidation, err.(FieldError).ErrorType)
+	assert.Equal(t, "min=0", err.(FieldError).Rule)
+	assert.Equal(t, "mem", err.(FieldError).Key)
+}
+
+func TestValidationRules(t *testing.T) {
+	s, err := ParseSchema(&ValidateStruct{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"min=2048", "max=8192"}, s.Lookup("bits").Rules)
+	// rules are listed in evaluation order
+	assert.Equal(t, []string{"nonempty", "max=8", "pattern=[a-z][a-z0-9-]*"}, s.Lookup("name").Rules)
+	assert.Equal(t, []string{"len=3"}, s.Lookup("code").Rules)
+	assert.Equal(t, []string{`pattern=\d{2,4}`}, s.Lookup("serial").Rules)
+
+	testCases := []struct {
+		dest     any
+		expected string
+	}{
+		{&ValidateInvalidLength{}, "invalid argv tag on field ValidateInvalidLength.Count: len requires a string, slice or map field"},
+		{&ValidateInvalidPattern{}, "invalid argv tag on field ValidateInvalidPattern.Name: invalid pattern '[a-z': error parsing regexp: missing closing ]: `[a-z`"},
+		{&ValidateInvalidBound{}, "invalid argv tag on field ValidateInvalidBound.Count: invalid min 'ten': strconv.ParseInt: parsing \"ten\": invalid syntax"},
+		{&ValidateInvalidRange{}, "invalid argv tag on field ValidateInvalidRange.Name: min is greater than max"},
+		{&ValidateInvalidType{}, "invalid argv tag on field ValidateInvalidType.Enabled: min requires a numeric, time, string, slice or map field"},
+		{&ValidateInvalidDefault{}, "invalid argv tag on field ValidateInvalidDefault.Bits: invalid default value: value 1024 is less than min=2048"},
+	}
+	for _, tc := range testCases {
+		_, err := ParseSchema(tc.dest)
+		assert.EqualError(t, err, tc.expected)
+	}
+}